@@ -0,0 +1,8 @@
+module github.com/aboelkassem/gophercises/quiz
+
+go 1.21
+
+require (
+	github.com/mattn/go-sqlite3 v1.14.22
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aboelkassem/gophercises/quiz/quiz"
+
+	_ "github.com/mattn/go-sqlite3" // load sqlite driver, _ for not unused in the code
+)
+
+const scoresFileName = "quiz.db"
+
+// defaultPlayer is the name of the logged in user
+func defaultPlayer() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "anonymous"
+}
+
+func openLeaderboard(path string) (*quiz.Leaderboard, *sql.DB, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	board, err := quiz.NewLeaderboard(db)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return board, db, nil
+}
+
+// saveScore stores a finished run of the quiz in the leaderboard
+func saveScore(dbPath, player, bankPath string, report quiz.Report, took time.Duration) error {
+	bank, err := quiz.HashFile(bankPath)
+	if err != nil {
+		return err
+	}
+
+	board, db, err := openLeaderboard(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return board.Save(&quiz.Score{
+		Player:   player,
+		Bank:     bank,
+		BankName: bankPath,
+		Score:    report.Score(),
+		Total:    report.Total(),
+		Duration: took,
+		PlayedAt: time.Now(),
+	})
+}
+
+// runLeaderboard prints the best results per question bank, or the results
+// of a player over time
+func runLeaderboard(args []string) {
+	fs := flag.NewFlagSet("leaderboard", flag.ExitOnError)
+	var (
+		flagDB       = fs.String("db", scoresFileName, "The path to the scores database")
+		flagFileName = fs.String("p", "", "Only show the results of this problems file")
+		flagPlayer   = fs.String("player", "", "Show the results of this player over time instead")
+		flagTop      = fs.Int("n", 10, "The number of results shown per question bank")
+	)
+	fs.Parse(args)
+
+	board, db, err := openLeaderboard(*flagDB)
+	if err != nil {
+		fmt.Printf("Failed to open scores %v", err)
+		return
+	}
+	defer db.Close()
+
+	var bank string
+	if *flagFileName != "" {
+		if bank, err = quiz.HashFile(*flagFileName); err != nil {
+			fmt.Printf("Failed to read problems file %v", err)
+			return
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	if *flagPlayer != "" {
+		scores, err := board.History(*flagPlayer, bank)
+		if err != nil {
+			fmt.Printf("Failed to load scores %v", err)
+			return
+		}
+		fmt.Fprintf(tw, "Results of %s\n", *flagPlayer)
+		fmt.Fprintln(tw, "Date\tBank\tScore\tTook")
+		for _, s := range scores {
+			fmt.Fprintf(tw, "%s\t%s (%s)\t%v/%d\t%v\n",
+				s.PlayedAt.Local().Format(time.DateTime), s.BankName, s.Bank[:7], round(s.Score), s.Total, s.Duration)
+		}
+		return
+	}
+
+	banks := []string{bank}
+	if bank == "" {
+		if banks, err = board.Banks(); err != nil {
+			fmt.Printf("Failed to load scores %v", err)
+			return
+		}
+	}
+
+	for _, bank := range banks {
+		scores, err := board.Top(bank, *flagTop)
+		if err != nil {
+			fmt.Printf("Failed to load scores %v", err)
+			return
+		}
+		if len(scores) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(tw, "%s (%s)\n", scores[0].BankName, bank[:7])
+		fmt.Fprintln(tw, "#\tPlayer\tScore\tTook\tDate")
+		for i, s := range scores {
+			fmt.Fprintf(tw, "%d\t%s\t%v/%d\t%v\t%s\n",
+				i+1, s.Player, round(s.Score), s.Total, s.Duration, s.PlayedAt.Local().Format(time.DateTime))
+		}
+		fmt.Fprintln(tw)
+	}
+}
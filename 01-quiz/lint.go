@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aboelkassem/gophercises/quiz/quiz"
+)
+
+// runLint checks the given question banks, or problems.csv by default, and
+// exits with status 1 when any of them has a problem
+func runLint(args []string) {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: quiz lint [files...]")
+	}
+	fs.Parse(args)
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{problemsFileName}
+	}
+
+	failed := false
+	for _, name := range files {
+		problems, err := lintFile(name)
+		if err != nil {
+			fmt.Printf("%s: %v\n", name, err)
+			failed = true
+			continue
+		}
+
+		for _, p := range problems {
+			if p.Line == 0 {
+				fmt.Printf("%s: %s\n", name, p.Message)
+			} else {
+				fmt.Printf("%s:%d: %s\n", name, p.Line, p.Message)
+			}
+		}
+		failed = failed || len(problems) > 0
+	}
+
+	if failed {
+		os.Exit(1) // error happened
+	}
+}
+
+func lintFile(name string) ([]quiz.Problem, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return quiz.Lint(name, file)
+}
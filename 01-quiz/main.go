@@ -1,25 +1,73 @@
 package main
 
 import (
-	"encoding/csv"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
+
+	"github.com/aboelkassem/gophercises/quiz/quiz"
 )
 
 const problemsFileName = "problems.csv"
 
-var (
-	correctAnswers, totalQuestions int
-)
+/*
+$ quiz [flags]
+	run a timed quiz
+
+$ quiz study [flags]
+	review the questions that are due, spaced repetition style
+
+$ quiz stats [flags]
+	show the mastery of every category of a question bank
+
+$ quiz leaderboard [flags]
+	show the best results per question bank, or the results of a player
+
+$ quiz lint [files...]
+	check question banks and report their problems with line numbers
+*/
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "study":
+			runStudy(os.Args[2:])
+			return
+		case "stats":
+			runStats(os.Args[2:])
+			return
+		case "leaderboard":
+			runLeaderboard(os.Args[2:])
+			return
+		case "lint":
+			runLint(os.Args[2:])
+			return
+		}
+	}
+
 	// flag package used to get arguments from user in runtime in cmd
 	var (
 		flagFileName = flag.String("p", problemsFileName, "The path to the problems csv file")
 		flagTimer    = flag.Duration("t", 30*time.Second, "The Max time for quiz")
+		// per question limit, a timeout column in the problems file wins over it
+		flagQuestionTimer = flag.Duration("qt", 0, "The Max time for each question, 0 for no limit")
+		flagReport        = flag.String("report", "", "Export the detailed report to this .csv or .json file")
+		flagShuffle       = flag.Bool("shuffle", false, "Ask the questions in a random order")
+		flagSeed          = flag.Int64("seed", 0, "The seed of the random sample and order, 0 for new ones every run")
+		flagSample        = flag.Int("n", 0, "Ask only N questions drawn at random, 0 for all of them")
+		flagTags          = flag.String("tags", "", "Comma separated tags, ask only the questions having one of them")
+		flagHTTP          = flag.String("http", "", "Serve the quiz as a web app on this address (like :8080) instead of the terminal")
+		flagName          = flag.String("name", defaultPlayer(), "The player name saved with the result")
+		flagDB            = flag.String("db", scoresFileName, "The path to the scores database, empty to not save the result")
 	)
 
 	// start reading/parsing the above defined flags
@@ -30,85 +78,111 @@ func main() {
 		return
 	}
 
-	fmt.Printf("Hit enter to start quiz form %q in %v\n", *flagFileName, *flagTimer)
-
-	fmt.Scanln() // wait for enter key
-
-	// x := 23 // to define varaibles into function scope (automatically go define the data type)
-	// var x int = 23 // explicitly define the datatype to int
-
-	// read csv file (problems.csv) using package
 	// flagFileName is pointer and this method take string not point, we use add * before it to convert it to normal string
 	// * is inverse of & (return/convert the pointer)
-	file, err := os.Open(*flagFileName)
+	questions, err := quiz.Load(*flagFileName)
 	if err != nil {
-		fmt.Printf("Failed to load file %v", err)
+		fmt.Printf("Failed to load questions %v", err)
 		return
 	}
-	defer file.Close() // run this line after the main function is done executing
 
-	r := csv.NewReader(file)
+	// the same seed gives the same questions in the same order with the
+	// same choices order
+	seed := *flagSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
 
-	questions, err := r.ReadAll()
-	if err != nil {
-		fmt.Printf("Failed to read csv file %v", err)
+	// -n draws its questions from rng on its own, -shuffle only changes the
+	// order they are asked in
+	filters := []quiz.Filter{
+		quiz.FilterTags(splitList(*flagTags)...),
+		quiz.FilterSample(rng, *flagSample),
+	}
+	if *flagShuffle {
+		filters = append(filters, quiz.FilterShuffle(rng))
+	}
+
+	questions = quiz.Select(questions, filters...)
+	if len(questions) == 0 {
+		fmt.Println("No questions to ask, check the -tags flag")
 		return
 	}
-	totalQuestions := len(questions)
 
-	// start 30 seconds timer
-	// will do it into goroutine and will return channel while executing
-	doneQuize := StartQuiz(questions)
+	opts := []quiz.Option{
+		quiz.OptionTimeout(*flagTimer),
+		quiz.OptionQuestionTimeout(*flagQuestionTimer),
+		quiz.OptionRand(rng),
+	}
 
-	// fire a timer in a separate channel
-	timerQuize := time.NewTimer(*flagTimer).C
+	if *flagHTTP != "" {
+		// every visitor gets its own session with the -t deadline
+		fmt.Printf("Serving quiz from %q on %s\n", *flagFileName, *flagHTTP)
+		log.Fatal(http.ListenAndServe(*flagHTTP, quiz.NewServer(questions, opts...)))
+	}
 
-	// select is like switch but for channels
-	select {
-	case <-doneQuize:
-		fmt.Println("all answers are done")
-	case <-timerQuize:
+	fmt.Printf("Hit enter to start quiz form %q in %v\n", *flagFileName, *flagTimer)
+
+	fmt.Scanln() // wait for enter key
+
+	// ctrl+c cancels the quiz but still prints the result so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	session := quiz.NewSession(questions, os.Stdin, os.Stdout, opts...)
+
+	// the session runs the quiz in its own goroutine, firing the quiz and
+	// question timers itself, and hands the report back over the channel
+	// once it is done writing it
+	start := time.Now()
+	result := <-session.Start(ctx)
+	took := time.Since(start)
+	report := result.Report
+
+	switch {
+	case errors.Is(result.Err, context.Canceled):
+		fmt.Println("the quiz was interrupted")
+	case result.Err != nil:
+		fmt.Printf("Failed to scan: %v\n", result.Err)
+	case report.TimedOut:
 		fmt.Println("the time is over")
+	default:
+		fmt.Println("all answers are done")
 	}
 
-	// output number of questions (total + correct)
-	fmt.Printf("Result: %d/%d \n", correctAnswers, totalQuestions)
+	// output every question then the number of questions (total + correct)
+	fmt.Println()
+	report.WriteText(os.Stdout)
+	fmt.Printf("Result: %v/%d \n", round(report.Score()), report.Total())
+
+	// only finished runs make it to the leaderboard
+	if result.Err == nil && *flagDB != "" {
+		if err := saveScore(*flagDB, *flagName, *flagFileName, report, took); err != nil {
+			fmt.Printf("Failed to save result %v\n", err)
+		}
+	}
+
+	if *flagReport != "" {
+		if err := report.Export(*flagReport); err != nil {
+			fmt.Printf("Failed to export report %v\n", err)
+		}
+	}
 }
 
-func StartQuiz(questions [][]string) chan bool {
-	// go keyword is use to define goroutine (a lighweight thread to be executed into parallel execution)
-	// - must written before function execution
-	// chan keyword (refer to channels) used to define channels to communicate between different goroutines
-	// Channels are pipes through which you can send and receive values using the channel operator, <- .
-	done := make(chan bool)
-
-	go func() {
-		for i, record := range questions {
-			var (
-				question      = record[0]
-				correctAnswer = record[1]
-			)
-
-			// display one question at a time
-			fmt.Printf("%d. %s?\n", i+1, question)
-			var userAnswer string
-
-			// get answer from, then proceed to the next question immediately
-			_, err := fmt.Scan(&userAnswer) // read input from user
-			if err != nil {
-				fmt.Printf("Failed to scan: %v", err)
-				return
-			}
-
-			userAnswer = strings.TrimSpace(userAnswer)
-			userAnswer = strings.ToLower(userAnswer)
-			if userAnswer == correctAnswer {
-				correctAnswers++
-			}
+// splitList splits a comma separated flag value, ignoring empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
 		}
-		// notify the main thread that we're done runnning the quiz
-		done <- true
-	}()
+	}
+	return items
+}
 
-	return done
+// round keeps 2 decimals at most of a score, partial credit can make it
+// fractional
+func round(score float64) float64 {
+	return math.Round(score*100) / 100
 }
@@ -0,0 +1,140 @@
+package quiz
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Eval evaluates an arithmetic expression made of numbers, parentheses,
+// unary minus and the + - * / operators with the usual precedence
+func Eval(expr string) (float64, error) {
+	p := &exprParser{s: expr}
+	v, err := p.sum()
+	if err != nil {
+		return 0, err
+	}
+	if p.skipSpaces(); p.pos < len(p.s) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.s[p.pos], p.pos+1)
+	}
+	return v, nil
+}
+
+// exprParser is a recursive descent parser over the grammar:
+//
+//	sum     = product { ("+" | "-") product }
+//	product = unary { ("*" | "/") unary }
+//	unary   = [ "-" | "+" ] unary | primary
+//	primary = number | "(" sum ")"
+type exprParser struct {
+	s   string
+	pos int
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+// peek returns the next non space byte, or 0 at the end of the input
+func (p *exprParser) peek() byte {
+	p.skipSpaces()
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *exprParser) sum() (float64, error) {
+	v, err := p.product()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch p.peek() {
+		case '+', '-':
+			op := p.s[p.pos]
+			p.pos++
+			rhs, err := p.product()
+			if err != nil {
+				return 0, err
+			}
+			if op == '+' {
+				v += rhs
+			} else {
+				v -= rhs
+			}
+		default:
+			return v, nil
+		}
+	}
+}
+
+func (p *exprParser) product() (float64, error) {
+	v, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch p.peek() {
+		case '*', '/':
+			op := p.s[p.pos]
+			p.pos++
+			rhs, err := p.unary()
+			if err != nil {
+				return 0, err
+			}
+			if op == '*' {
+				v *= rhs
+			} else {
+				if rhs == 0 {
+					return 0, fmt.Errorf("division by zero")
+				}
+				v /= rhs
+			}
+		default:
+			return v, nil
+		}
+	}
+}
+
+func (p *exprParser) unary() (float64, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		v, err := p.unary()
+		return -v, err
+	case '+':
+		p.pos++
+		return p.unary()
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (float64, error) {
+	switch c := p.peek(); {
+	case c == 0:
+		return 0, fmt.Errorf("unexpected end of expression")
+	case c == '(':
+		p.pos++
+		v, err := p.sum()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("missing ) at position %d", p.pos+1)
+		}
+		p.pos++
+		return v, nil
+	case c == '.' || c >= '0' && c <= '9':
+		start := p.pos
+		for p.pos < len(p.s) && strings.IndexByte("0123456789.", p.s[p.pos]) >= 0 {
+			p.pos++
+		}
+		return strconv.ParseFloat(p.s[start:p.pos], 64)
+	default:
+		return 0, fmt.Errorf("unexpected %q at position %d", c, p.pos+1)
+	}
+}
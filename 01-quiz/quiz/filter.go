@@ -0,0 +1,76 @@
+package quiz
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Filter transforms the list of questions of a bank into the list asked
+type Filter func([]Question) []Question
+
+// Select applies filters in order to a copy of questions
+func Select(questions []Question, filters ...Filter) []Question {
+	selected := append([]Question(nil), questions...)
+	for _, filter := range filters {
+		selected = filter(selected)
+	}
+	return selected
+}
+
+// FilterTags keeps the questions having at least one of tags, it keeps
+// everything when no tag is given
+func FilterTags(tags ...string) Filter {
+	return func(questions []Question) []Question {
+		if len(tags) == 0 {
+			return questions
+		}
+		var kept []Question
+		for _, q := range questions {
+			if q.HasTag(tags...) {
+				kept = append(kept, q)
+			}
+		}
+		return kept
+	}
+}
+
+// FilterShuffle puts the questions in a random order taken from rng
+func FilterShuffle(rng *rand.Rand) Filter {
+	return func(questions []Question) []Question {
+		rng.Shuffle(len(questions), func(i, j int) {
+			questions[i], questions[j] = questions[j], questions[i]
+		})
+		return questions
+	}
+}
+
+// FilterSample keeps n questions drawn at random from rng, in their order
+// in the bank so FilterShuffle alone decides the order they are asked in.
+// Zero or a negative n keeps everything
+func FilterSample(rng *rand.Rand, n int) Filter {
+	return func(questions []Question) []Question {
+		if n <= 0 || n >= len(questions) {
+			return questions
+		}
+		picked := rng.Perm(len(questions))[:n]
+		sort.Ints(picked)
+		kept := make([]Question, 0, n)
+		for _, i := range picked {
+			kept = append(kept, questions[i])
+		}
+		return kept
+	}
+}
+
+// HasTag reports whether q is tagged with one of tags, case insensitively
+func (q Question) HasTag(tags ...string) bool {
+	for _, tag := range tags {
+		for _, t := range q.Tags {
+			if strings.EqualFold(strings.TrimSpace(t), strings.TrimSpace(tag)) {
+				return true
+			}
+		}
+	}
+	return false
+}
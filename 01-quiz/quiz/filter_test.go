@@ -0,0 +1,59 @@
+package quiz
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	questions := []Question{
+		{Text: "1+1", Answer: "2", Tags: []string{"math"}},
+		{Text: "Go was created at", Answer: "google", Tags: []string{"Go", "history"}},
+		{Text: "2+2", Answer: "4", Tags: []string{"math"}},
+		{Text: "Capital of France", Answer: "paris"},
+	}
+
+	if got := Select(questions, FilterTags("go", "history")); len(got) != 1 || got[0].Text != "Go was created at" {
+		t.Errorf("FilterTags(go, history): got %v", got)
+	}
+	if got := Select(questions, FilterTags()); len(got) != len(questions) {
+		t.Errorf("FilterTags(): want every question, got %d", len(got))
+	}
+	if got := Select(questions, FilterSample(rand.New(rand.NewSource(1)), 0)); len(got) != len(questions) {
+		t.Errorf("FilterSample(0): want every question, got %d", len(got))
+	}
+
+	s1 := Select(questions, FilterSample(rand.New(rand.NewSource(7)), 3), FilterShuffle(rand.New(rand.NewSource(7))))
+	s2 := Select(questions, FilterSample(rand.New(rand.NewSource(7)), 3), FilterShuffle(rand.New(rand.NewSource(7))))
+	for i := range s1 {
+		if s1[i].Text != s2[i].Text {
+			t.Fatalf("FilterShuffle: want the same order for the same seed, got %v and %v", s1, s2)
+		}
+	}
+
+	// without shuffling the sample is random but asked in the bank order
+	seen := map[string]bool{}
+	for seed := int64(1); seed <= 20; seed++ {
+		got := Select(questions, FilterSample(rand.New(rand.NewSource(seed)), 2))
+		if len(got) != 2 || index(questions, got[0]) >= index(questions, got[1]) {
+			t.Fatalf("FilterSample(2) seed %d: want 2 questions in the bank order, got %v", seed, got)
+		}
+		seen[got[0].Text] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("FilterSample(2): want samples not always starting with the first question, got %v", seen)
+	}
+	if questions[0].Text != "1+1" || questions[3].Text != "Capital of France" {
+		t.Errorf("Select: want the bank untouched, got %v", questions)
+	}
+}
+
+// index returns the position of q in questions, or -1
+func index(questions []Question, q Question) int {
+	for i, other := range questions {
+		if other.Text == q.Text {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,277 @@
+package quiz
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is an issue found in a question bank by Lint
+type Problem struct {
+	Line    int // 0 when the problem is about the whole bank
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+}
+
+// fields are the names of the Question fields in every format
+var fields = []string{"question", "answer", "timeout", "match", "type", "choices", "tags"}
+
+// Lint checks the question bank read from r, picking the format from the
+// extension of name like SourceFor does, and returns its problems sorted by
+// line: missing or unknown fields, duplicate questions, answers that can
+// never match and invalid question options
+func Lint(name string, r io.Reader) ([]Problem, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linter{seen: map[string]int{}}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		l.json(data)
+	case ".yaml", ".yml":
+		l.yaml(data)
+	default:
+		l.csv(data)
+	}
+
+	sort.SliceStable(l.problems, func(i, j int) bool {
+		return l.problems[i].Line < l.problems[j].Line
+	})
+	return l.problems, nil
+}
+
+type linter struct {
+	problems []Problem
+	seen     map[string]int // line of every question text seen
+}
+
+func (l *linter) addf(line int, format string, a ...any) {
+	l.problems = append(l.problems, Problem{Line: line, Message: fmt.Sprintf(format, a...)})
+}
+
+func (l *linter) csv(data []byte) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+
+	columns, width := defaultColumns, len(defaultColumns)
+	for first := true; ; first = false {
+		record, err := r.Read()
+		if err == io.EOF {
+			return
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			l.addf(parseErr.Line, "invalid CSV: %v", parseErr.Err)
+			return
+		}
+		if err != nil {
+			l.addf(0, "invalid CSV: %v", err)
+			return
+		}
+		line, _ := r.FieldPos(0)
+
+		if first && isHeader(record) {
+			columns, width = make(csvColumns, len(record)), len(record)
+			for i, name := range record {
+				name = strings.ToLower(strings.TrimSpace(name))
+				if !known(name) {
+					l.addf(line, "unknown column %q", name)
+				}
+				if _, ok := columns[name]; ok {
+					l.addf(line, "duplicate column %q", name)
+				}
+				columns[name] = i
+			}
+			continue
+		}
+
+		if len(record) > width {
+			l.addf(line, "%d extra field(s), expected at most %d", len(record)-width, width)
+		}
+		if _, ok := columns.field(record, "answer"); !ok {
+			l.addf(line, "missing answer field")
+			continue
+		}
+
+		q, err := columns.question(record)
+		l.question(line, q, err)
+	}
+}
+
+func (l *linter) json(data []byte) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	lineAt := func(offset int64) int {
+		return bytes.Count(data[:offset], []byte("\n")) + 1
+	}
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		l.addf(lineAt(dec.InputOffset()), "invalid JSON: expected an array of questions")
+		return
+	}
+
+	for dec.More() {
+		// InputOffset is right after the previous value, skip to the object
+		offset := dec.InputOffset()
+		for offset < int64(len(data)) && bytes.IndexByte([]byte(" \t\r\n,"), data[offset]) >= 0 {
+			offset++
+		}
+		line := lineAt(offset)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			l.addf(line, "invalid JSON: %v", err)
+			return
+		}
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(value, &raw); err != nil {
+			l.addf(line, "invalid JSON: expected a question")
+			continue
+		}
+		l.keys(line, keysOf(raw))
+
+		var q Question
+		err := json.Unmarshal(value, &q)
+		if _, ok := raw["answer"]; !ok {
+			l.addf(line, "missing answer field")
+			continue
+		}
+		if err == nil {
+			err = q.validate()
+		}
+		l.question(line, q, err)
+	}
+}
+
+func (l *linter) yaml(data []byte) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		l.addf(0, "invalid YAML: %v", err)
+		return
+	}
+	if len(doc.Content) == 0 {
+		return
+	}
+	list := doc.Content[0]
+	if list.Kind != yaml.SequenceNode {
+		l.addf(list.Line, "invalid YAML: expected a list of questions")
+		return
+	}
+
+	for _, node := range list.Content {
+		if node.Kind != yaml.MappingNode {
+			l.addf(node.Line, "invalid YAML: expected a question")
+			continue
+		}
+
+		var keys []string
+		for i := 0; i < len(node.Content); i += 2 {
+			keys = append(keys, node.Content[i].Value)
+		}
+		l.keys(node.Line, keys)
+
+		var q Question
+		err := node.Decode(&q)
+		if !contains(keys, "answer") {
+			l.addf(node.Line, "missing answer field")
+			continue
+		}
+		if err == nil {
+			err = q.validate()
+		}
+		l.question(node.Line, q, err)
+	}
+}
+
+// keys reports the unknown fields of a question
+func (l *linter) keys(line int, keys []string) {
+	for _, key := range keys {
+		if !known(key) {
+			l.addf(line, "unknown field %q", key)
+		}
+	}
+}
+
+// question reports the problems of a decoded question, err is the decoding
+// or validation error of q
+func (l *linter) question(line int, q Question, err error) {
+	if err != nil {
+		l.addf(line, "%v", err)
+	}
+
+	text := strings.ToLower(strings.TrimSpace(q.Text))
+	if text == "" {
+		l.addf(line, "missing question text")
+	} else if first, ok := l.seen[text]; ok {
+		l.addf(line, "duplicate question %q, first seen on line %d", q.Text, first)
+	} else {
+		l.seen[text] = line
+	}
+
+	if strings.TrimSpace(q.Answer) == "" {
+		l.addf(line, "empty answer")
+		return
+	}
+
+	// exact matching lowercases and trims the answer of the user, but not the
+	// expected one
+	if q.kind() != TypeText {
+		return
+	}
+	switch kind, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(q.Match)), ":"); kind {
+	case "", "exact", "any":
+		answers := []string{q.Answer}
+		if kind == "any" {
+			answers = strings.Split(q.Answer, "|")
+		}
+		for _, a := range answers {
+			if kind == "any" {
+				a = strings.TrimSpace(a)
+			}
+			switch {
+			case strings.IndexFunc(a, unicode.IsUpper) >= 0:
+				l.addf(line, "answer %q has uppercase letters and can never match, answers are compared lowercased", a)
+			case a != strings.TrimSpace(a):
+				l.addf(line, "answer %q has surrounding spaces and can never match, answers are compared trimmed", a)
+			}
+		}
+	}
+}
+
+func known(field string) bool {
+	return contains(fields, field)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func keysOf(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
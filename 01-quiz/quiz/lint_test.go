@@ -0,0 +1,81 @@
+package quiz
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		problems []string
+	}{
+		{
+			name: "problems.csv",
+			data: "5+5,10\n" +
+				"1+1\n" +
+				"5+5,10\n" +
+				"Capital of France,Paris\n" +
+				"2+2,4,10s,exact,text,,math,oops\n" +
+				"3+3,6,soon\n" +
+				"4+4,8,,regex(\n",
+			problems: []string{
+				"line 2: missing answer field",
+				`line 3: duplicate question "5+5", first seen on line 1`,
+				`line 4: answer "Paris" has uppercase letters`,
+				"line 5: 1 extra field(s), expected at most 7",
+				`line 6: invalid timeout "soon"`,
+				`line 7: unknown matcher "regex("`,
+			},
+		},
+		{
+			name: "header.csv",
+			data: "question,answer,level\n" +
+				"Go was created at,google,1\n",
+			problems: []string{`line 1: unknown column "level"`},
+		},
+		{
+			name: "problems.json",
+			data: `[
+  {"question": "5+5", "answer": "10"},
+  {"question": "Go was created at", "type": "choice", "choices": ["Google", "Apple"], "answer": "Mozilla"},
+  {"question": "1+1", "anwser": "2"}
+]`,
+			problems: []string{
+				`line 3: answer "Mozilla" is not one of the choices`,
+				`line 4: unknown field "anwser"`,
+				"line 4: missing answer field",
+			},
+		},
+		{
+			name: "problems.yaml",
+			data: "- question: 5+5\n" +
+				"  answer: 10\n" +
+				"- question: 5+5\n" +
+				"  answer: 10\n" +
+				"  type: bool\n",
+			problems: []string{
+				`line 3: answer "10" of a bool question must be true or false`,
+				`line 3: duplicate question "5+5", first seen on line 1`,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			problems, err := Lint(c.name, strings.NewReader(c.data))
+			if err != nil {
+				t.Fatalf("Lint() received an error: %s", err.Error())
+			}
+			if len(problems) != len(c.problems) {
+				t.Fatalf("Lint(): want %d problems, got %v", len(c.problems), problems)
+			}
+			for i, p := range problems {
+				if !strings.HasPrefix(p.String(), c.problems[i]) {
+					t.Errorf("problem %d: want %q, got %q", i, c.problems[i], p.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package quiz
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Matcher decides whether an answer given by the user is accepted
+type Matcher interface {
+	Match(answer string) bool
+}
+
+// MatcherFunc is an adapter to use an ordinary function as a Matcher
+type MatcherFunc func(answer string) bool
+
+// Match implements Matcher
+func (f MatcherFunc) Match(answer string) bool {
+	return f(answer)
+}
+
+// NewMatcher builds the Matcher described by spec for the expected answer.
+//
+// spec is the kind of matching optionally followed by a colon and a
+// parameter:
+//
+//	exact        the lowercased answer equals expected (the default)
+//	any          like exact against any of the "|" separated expected answers
+//	numeric[:t]  the answer is a number within t (default 0) of expected
+//	expr[:t]     like numeric, but both sides can be arithmetic expressions
+//	regex        the whole answer matches the expected regular expression
+//	fuzzy[:d]    the answer is at most d (default 1) edits away from expected
+func NewMatcher(spec, expected string) (Matcher, error) {
+	kind, param, _ := strings.Cut(strings.TrimSpace(spec), ":")
+
+	switch strings.ToLower(kind) {
+	case "", "exact":
+		return MatcherFunc(func(answer string) bool {
+			return normalize(answer) == expected
+		}), nil
+
+	case "any":
+		accepted := strings.Split(expected, "|")
+		return MatcherFunc(func(answer string) bool {
+			answer = normalize(answer)
+			for _, a := range accepted {
+				if answer == strings.TrimSpace(a) {
+					return true
+				}
+			}
+			return false
+		}), nil
+
+	case "numeric", "expr":
+		tolerance, err := parseParam(param, 0)
+		if err != nil {
+			return nil, fmt.Errorf("invalid matcher %q: %v", spec, err)
+		}
+		parse := parseNumber
+		if strings.EqualFold(kind, "expr") {
+			parse = Eval
+		}
+		want, err := parse(expected)
+		if err != nil {
+			return nil, fmt.Errorf("invalid answer %q for matcher %q: %v", expected, spec, err)
+		}
+		return MatcherFunc(func(answer string) bool {
+			got, err := parse(answer)
+			return err == nil && math.Abs(got-want) <= tolerance
+		}), nil
+
+	case "regex":
+		re, err := regexp.Compile(`^(?:` + expected + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid answer %q for matcher %q: %v", expected, spec, err)
+		}
+		return MatcherFunc(func(answer string) bool {
+			return re.MatchString(strings.TrimSpace(answer))
+		}), nil
+
+	case "fuzzy":
+		distance, err := parseParam(param, 1)
+		if err != nil {
+			return nil, fmt.Errorf("invalid matcher %q: %v", spec, err)
+		}
+		expected = strings.ToLower(expected)
+		return MatcherFunc(func(answer string) bool {
+			return float64(levenshtein(normalize(answer), expected)) <= distance
+		}), nil
+	}
+
+	return nil, fmt.Errorf("unknown matcher %q", spec)
+}
+
+// normalize is the comparison form of an answer typed by the user
+func normalize(answer string) string {
+	answer = strings.TrimSpace(answer)
+	return strings.ToLower(answer)
+}
+
+func parseParam(param string, def float64) (float64, error) {
+	if param == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseFloat(param, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("parameter %q must be a positive number", param)
+	}
+	return v, nil
+}
+
+func parseNumber(s string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(s), 64)
+}
+
+// levenshtein returns the number of single rune insertions, deletions or
+// substitutions needed to turn a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// only keep the previous row of the distance matrix
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,60 @@
+package quiz
+
+import "testing"
+
+func TestNewMatcher(t *testing.T) {
+	cases := []struct {
+		name     string
+		spec     string
+		expected string
+		answer   string
+		match    bool
+	}{
+		{name: "exact", spec: "", expected: "go", answer: " Go ", match: true},
+		{name: "exact: multi word", spec: "exact", expected: "hello world", answer: "Hello World", match: true},
+		{name: "exact: wrong", spec: "exact", expected: "go", answer: "golang", match: false},
+		{name: "any", spec: "any", expected: "color|colour", answer: "Colour", match: true},
+		{name: "any: wrong", spec: "any", expected: "color|colour", answer: "colr", match: false},
+		{name: "numeric", spec: "numeric", expected: "10", answer: "10.0", match: true},
+		{name: "numeric: tolerance", spec: "numeric:0.01", expected: "3.14159", answer: "3.14", match: true},
+		{name: "numeric: out of tolerance", spec: "numeric:0.001", expected: "3.14159", answer: "3.14", match: false},
+		{name: "numeric: not a number", spec: "numeric", expected: "10", answer: "ten", match: false},
+		{name: "expr", spec: "expr", expected: "2*(3+2)", answer: "20 / 2", match: true},
+		{name: "expr: unary minus", spec: "expr", expected: "-4", answer: "1 - -1 * -5", match: true},
+		{name: "expr: wrong", spec: "expr", expected: "10", answer: "5+6", match: false},
+		{name: "regex", spec: "regex", expected: `(?i)go(lang)?`, answer: "GoLang", match: true},
+		{name: "regex: anchored", spec: "regex", expected: `go`, answer: "golang", match: false},
+		{name: "fuzzy", spec: "fuzzy", expected: "Paris", answer: "pari", match: true},
+		{name: "fuzzy: distance", spec: "fuzzy:2", expected: "kitten", answer: "sitting", match: false},
+		{name: "fuzzy: distance ok", spec: "fuzzy:3", expected: "kitten", answer: "sitting", match: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m, err := NewMatcher(c.spec, c.expected)
+			if err != nil {
+				t.Fatalf("NewMatcher(%q, %q) received an error: %s", c.spec, c.expected, err.Error())
+			}
+			if got := m.Match(c.answer); got != c.match {
+				t.Errorf("Match(%q) == %v, expected %v", c.answer, got, c.match)
+			}
+		})
+	}
+}
+
+func TestNewMatcher_Invalid(t *testing.T) {
+	cases := []struct{ spec, expected string }{
+		{spec: "unknown", expected: "10"},
+		{spec: "numeric", expected: "ten"},
+		{spec: "numeric:x", expected: "10"},
+		{spec: "expr", expected: "2*(3+"},
+		{spec: "regex", expected: "go("},
+		{spec: "fuzzy:-1", expected: "go"},
+	}
+
+	for _, c := range cases {
+		if _, err := NewMatcher(c.spec, c.expected); err == nil {
+			t.Errorf("NewMatcher(%q, %q): want an error, got nil", c.spec, c.expected)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// Package quiz implements a terminal quiz engine that reads its questions
+// from a pluggable Source (CSV, JSON or YAML) and runs them against any
+// io.Reader/io.Writer pair
+package quiz
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Question is a single quiz entry and the answer expected for it
+type Question struct {
+	Text   string `json:"question" yaml:"question"`
+	Answer string `json:"answer" yaml:"answer"`
+	// Timeout bounds the time allowed to answer this question, zero means
+	// the session default is used
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+	// Match is the Matcher spec used to check answers, see NewMatcher
+	Match string `json:"match" yaml:"match"`
+	// Type is one of the Type constants, empty means TypeText
+	Type string `json:"type" yaml:"type"`
+	// Choices are the options offered by choice and multi questions, they
+	// are shown in a random order
+	Choices []string `json:"choices" yaml:"choices"`
+	// Tags are the categories of the question, used to select a subset of
+	// a bank
+	Tags []string `json:"tags" yaml:"tags"`
+}
+
+// Matcher returns the Matcher checking the answers to q
+func (q Question) Matcher() (Matcher, error) {
+	return NewMatcher(q.Match, q.Answer)
+}
+
+// Check reports whether answer is accepted for q, an invalid matcher spec
+// accepts nothing
+func (q Question) Check(answer string) bool {
+	m, err := q.Matcher()
+	return err == nil && m.Match(answer)
+}
+
+// validate reports the options of q that can't be used to run it
+func (q Question) validate() error {
+	return q.validateType()
+}
+
+// validateAll validates every question, numbering them from 1 in errors
+func validateAll(questions []Question) error {
+	for i, q := range questions {
+		if err := q.validate(); err != nil {
+			return fmt.Errorf("question %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON lets the timeout be written as a duration string like "10s"
+func (q *Question) UnmarshalJSON(b []byte) error {
+	type question Question // same fields without the UnmarshalJSON method
+	aux := struct {
+		*question
+		Timeout string `json:"timeout"`
+	}{question: (*question)(q)}
+
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	timeout, err := parseTimeout(aux.Timeout)
+	if err != nil {
+		return err
+	}
+	q.Timeout = timeout
+	return nil
+}
+
+func parseTimeout(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %v", s, err)
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must not be negative", s)
+	}
+	return timeout, nil
+}
+
+// Source is anything able to provide the questions of a quiz
+type Source interface {
+	Questions() ([]Question, error)
+}
+
+// CSVSource reads questions from CSV records in the form
+// `question,answer[,timeout[,match[,type[,choices[,tags]]]]]`.
+//
+// The first record can also be a header naming the columns, which then may
+// come in any order. The choices of a question, the answers of a multi
+// question and the tags are separated by "|":
+//
+//	question,type,choices,answer,tags
+//	5+5,,,10,math
+//	Go was created at,choice,Google|Microsoft|Apple,Google,go|history
+//	Pick the primes,multi,2|4|5|9,2|5,math
+//	Go has generics,bool,,true,go
+type CSVSource struct {
+	r io.Reader
+}
+
+// NewCSVSource returns a Source that parses the CSV data read from r
+func NewCSVSource(r io.Reader) *CSVSource {
+	return &CSVSource{r: r}
+}
+
+// Questions implements Source
+func (s *CSVSource) Questions() ([]Question, error) {
+	r := csv.NewReader(s.r)
+	r.FieldsPerRecord = -1 // only the question and answer columns are required
+
+	var (
+		questions []Question
+		columns   = defaultColumns
+	)
+	for first := true; ; first = false {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+
+		if first && isHeader(record) {
+			columns = make(map[string]int, len(record))
+			for i, name := range record {
+				columns[strings.ToLower(strings.TrimSpace(name))] = i
+			}
+			continue
+		}
+
+		q, err := columns.question(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		questions = append(questions, q)
+	}
+	return questions, nil
+}
+
+// csvColumns maps a column name to its index in a CSV record
+type csvColumns map[string]int
+
+// defaultColumns is the column layout of a CSV file without a header
+var defaultColumns = csvColumns{"question": 0, "answer": 1, "timeout": 2, "match": 3, "type": 4, "choices": 5, "tags": 6}
+
+func (c csvColumns) field(record []string, name string) (string, bool) {
+	i, ok := c[name]
+	if !ok || i >= len(record) {
+		return "", false
+	}
+	return record[i], true
+}
+
+func (c csvColumns) question(record []string) (Question, error) {
+	var q Question
+
+	text, ok := c.field(record, "question")
+	if !ok {
+		return q, fmt.Errorf("missing question field")
+	}
+	answer, ok := c.field(record, "answer")
+	if !ok {
+		return q, fmt.Errorf("missing answer field")
+	}
+	timeout, _ := c.field(record, "timeout")
+	match, _ := c.field(record, "match")
+	kind, _ := c.field(record, "type")
+	choices, _ := c.field(record, "choices")
+	tags, _ := c.field(record, "tags")
+
+	q.Text, q.Answer, q.Match, q.Type = text, answer, match, strings.TrimSpace(kind)
+	if choices = strings.TrimSpace(choices); choices != "" {
+		q.Choices = strings.Split(choices, "|")
+	}
+	if tags = strings.TrimSpace(tags); tags != "" {
+		q.Tags = strings.Split(tags, "|")
+	}
+	var err error
+	if q.Timeout, err = parseTimeout(timeout); err != nil {
+		return q, err
+	}
+	return q, q.validate()
+}
+
+// isHeader reports whether record names the question and answer columns
+func isHeader(record []string) bool {
+	var question, answer bool
+	for _, name := range record {
+		name = strings.ToLower(strings.TrimSpace(name))
+		question = question || name == "question"
+		answer = answer || name == "answer"
+	}
+	return question && answer
+}
+
+// JSONSource reads questions from a JSON array of
+// `{"question": "...", "answer": "...", "timeout": "10s", "match": "exact",
+// "type": "choice", "choices": ["...", "..."], "tags": ["..."]}` objects
+type JSONSource struct {
+	r io.Reader
+}
+
+// NewJSONSource returns a Source that decodes the JSON data read from r
+func NewJSONSource(r io.Reader) *JSONSource {
+	return &JSONSource{r: r}
+}
+
+// Questions implements Source
+func (s *JSONSource) Questions() ([]Question, error) {
+	var questions []Question
+	if err := json.NewDecoder(s.r).Decode(&questions); err != nil {
+		return nil, err
+	}
+	return questions, validateAll(questions)
+}
+
+// YAMLSource reads questions from a YAML list in the form:
+//
+//   - question: 5+5
+//     answer: 10
+//     timeout: 10s
+//     match: numeric
+//     tags: [math]
+//   - question: Go was created at
+//     type: choice
+//     choices: [Google, Microsoft, Apple]
+//     answer: Google
+type YAMLSource struct {
+	r io.Reader
+}
+
+// NewYAMLSource returns a Source that decodes the YAML data read from r
+func NewYAMLSource(r io.Reader) *YAMLSource {
+	return &YAMLSource{r: r}
+}
+
+// Questions implements Source
+func (s *YAMLSource) Questions() ([]Question, error) {
+	var questions []Question
+	if err := yaml.NewDecoder(s.r).Decode(&questions); err != nil {
+		return nil, err
+	}
+	return questions, validateAll(questions)
+}
+
+// Load reads the questions stored in the file at path, picking the Source
+// from the file extension (.json, .yaml/.yml, anything else is read as CSV)
+func Load(path string) ([]Question, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return SourceFor(path, file).Questions()
+}
+
+// SourceFor returns the Source matching the extension of name reading from r
+func SourceFor(name string, r io.Reader) Source {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return NewJSONSource(r)
+	case ".yaml", ".yml":
+		return NewYAMLSource(r)
+	default:
+		return NewCSVSource(r)
+	}
+}
@@ -0,0 +1,143 @@
+package quiz
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Answer is what happened to one question of a quiz run
+type Answer struct {
+	Question string        `json:"question"`
+	Given    string        `json:"answer"`
+	Expected string        `json:"expected"`
+	Answered bool          `json:"answered"`
+	Correct  bool          `json:"correct"`
+	Points   float64       `json:"points"`
+	TimedOut bool          `json:"timed_out"`
+	Took     time.Duration `json:"took"`
+}
+
+// MarshalJSON writes Took as a duration string like "1.5s" instead of
+// nanoseconds
+func (a Answer) MarshalJSON() ([]byte, error) {
+	type answer Answer // same fields without the MarshalJSON method
+	return json.Marshal(struct {
+		answer
+		Took string `json:"took"`
+	}{answer(a), a.Took.Round(time.Millisecond).String()})
+}
+
+// Report is the outcome of a quiz run, one Answer per question in the order
+// they were asked
+type Report struct {
+	Answers []Answer `json:"answers"`
+	// TimedOut is set when the global quiz timeout ended the run
+	TimedOut bool `json:"timed_out"`
+}
+
+// Correct returns the number of correctly answered questions
+func (r Report) Correct() int {
+	correct := 0
+	for _, a := range r.Answers {
+		if a.Correct {
+			correct++
+		}
+	}
+	return correct
+}
+
+// Score returns the sum of the points earned, partial credit included
+func (r Report) Score() float64 {
+	score := 0.0
+	for _, a := range r.Answers {
+		score += a.Points
+	}
+	return score
+}
+
+// Total returns the number of questions in the quiz
+func (r Report) Total() int {
+	return len(r.Answers)
+}
+
+// WriteText writes the report as an aligned table for the terminal
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tQuestion\tAnswer\tExpected\tResult\tPoints\tTook")
+	for i, a := range r.Answers {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%.2g\t%v\n",
+			i+1, a.Question, a.Given, a.Expected, a.Result(), a.Points, a.Took.Round(time.Millisecond))
+	}
+	return tw.Flush()
+}
+
+// WriteCSV writes the report as CSV with a header row
+func (r Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"question", "answer", "expected", "answered", "correct", "points", "timed_out", "took"})
+	for _, a := range r.Answers {
+		cw.Write([]string{
+			a.Question,
+			a.Given,
+			a.Expected,
+			strconv.FormatBool(a.Answered),
+			strconv.FormatBool(a.Correct),
+			strconv.FormatFloat(a.Points, 'g', -1, 64),
+			strconv.FormatBool(a.TimedOut),
+			a.Took.Round(time.Millisecond).String(),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the report as an indented JSON document
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// Export writes the report into the file at path, as JSON when the file
+// extension is .json and as CSV otherwise
+func (r Report) Export(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = r.WriteJSON(file)
+	} else {
+		err = r.WriteCSV(file)
+	}
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Result describes the outcome of the answer in a word
+func (a Answer) Result() string {
+	switch {
+	case a.Correct:
+		return "correct"
+	case a.Points > 0:
+		return "partial"
+	case a.TimedOut:
+		return "timed out"
+	case !a.Answered:
+		return "unanswered"
+	default:
+		return "wrong"
+	}
+}
@@ -0,0 +1,138 @@
+package quiz
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"os"
+	"time"
+)
+
+// Score is the result of one finished quiz run
+type Score struct {
+	ID       int64
+	Player   string
+	Bank     string // hash of the question bank, see HashFile
+	BankName string // path of the question bank when it was played
+	Score    float64
+	Total    int
+	Duration time.Duration
+	PlayedAt time.Time
+}
+
+// Leaderboard stores the scores of the quiz runs in a SQL database. It is
+// written against SQLite, the driver is left for the caller to register
+type Leaderboard struct {
+	db *sql.DB
+}
+
+// NewLeaderboard returns a Leaderboard storing its scores in db, creating
+// its table when needed
+func NewLeaderboard(db *sql.DB) (*Leaderboard, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS scores (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			player      TEXT NOT NULL,
+			bank        TEXT NOT NULL,
+			bank_name   TEXT NOT NULL,
+			score       REAL NOT NULL,
+			total       INTEGER NOT NULL,
+			duration_ms INTEGER NOT NULL,
+			played_at   TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS scores_bank_score ON scores (bank, score DESC);
+		CREATE INDEX IF NOT EXISTS scores_player ON scores (player, played_at);
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return &Leaderboard{db: db}, nil
+}
+
+// Save stores s and sets its ID
+func (l *Leaderboard) Save(s *Score) error {
+	res, err := l.db.Exec(
+		`INSERT INTO scores (player, bank, bank_name, score, total, duration_ms, played_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		s.Player, s.Bank, s.BankName, s.Score, s.Total, s.Duration.Milliseconds(), s.PlayedAt.UTC(),
+	)
+	if err != nil {
+		return err
+	}
+	s.ID, err = res.LastInsertId()
+	return err
+}
+
+// Banks returns the hashes of the question banks played, most recently
+// played first
+func (l *Leaderboard) Banks() ([]string, error) {
+	rows, err := l.db.Query(`SELECT bank FROM scores GROUP BY bank ORDER BY MAX(played_at) DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var banks []string
+	for rows.Next() {
+		var bank string
+		if err := rows.Scan(&bank); err != nil {
+			return nil, err
+		}
+		banks = append(banks, bank)
+	}
+	return banks, rows.Err()
+}
+
+// Top returns the limit best scores of a question bank. The runs asked a
+// sample of the bank, with -n or -tags, so they are ranked by the share of
+// their questions answered, then the most questions and the fastest first
+func (l *Leaderboard) Top(bank string, limit int) ([]Score, error) {
+	return l.query(`WHERE bank = ? ORDER BY score / total DESC, total DESC, duration_ms ASC, played_at ASC LIMIT ?`, bank, limit)
+}
+
+// History returns every score of player in the order they were played, only
+// on the given question bank unless bank is empty
+func (l *Leaderboard) History(player, bank string) ([]Score, error) {
+	if bank == "" {
+		return l.query(`WHERE player = ? ORDER BY played_at ASC`, player)
+	}
+	return l.query(`WHERE player = ? AND bank = ? ORDER BY played_at ASC`, player, bank)
+}
+
+func (l *Leaderboard) query(where string, args ...any) ([]Score, error) {
+	rows, err := l.db.Query(`SELECT id, player, bank, bank_name, score, total, duration_ms, played_at FROM scores `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scores []Score
+	for rows.Next() {
+		var (
+			s  Score
+			ms int64
+		)
+		if err := rows.Scan(&s.ID, &s.Player, &s.Bank, &s.BankName, &s.Score, &s.Total, &ms, &s.PlayedAt); err != nil {
+			return nil, err
+		}
+		s.Duration = time.Duration(ms) * time.Millisecond
+		scores = append(scores, s)
+	}
+	return scores, rows.Err()
+}
+
+// HashFile identifies the content of a question bank, so scores made on
+// different versions of a bank are not ranked together
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
@@ -0,0 +1,72 @@
+package quiz
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestLeaderboard(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "quiz.db"))
+	if err != nil {
+		t.Fatalf("sql.Open() received an error: %s", err.Error())
+	}
+	defer db.Close()
+
+	board, err := NewLeaderboard(db)
+	if err != nil {
+		t.Fatalf("NewLeaderboard() received an error: %s", err.Error())
+	}
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	scores := []Score{
+		{Player: "ann", Bank: "math", Score: 8, Total: 10, Duration: 20 * time.Second, PlayedAt: start},
+		{Player: "bob", Bank: "math", Score: 9, Total: 10, Duration: 25 * time.Second, PlayedAt: start.Add(time.Hour)},
+		{Player: "ann", Bank: "math", Score: 9, Total: 10, Duration: 15 * time.Second, PlayedAt: start.Add(2 * time.Hour)},
+		{Player: "ann", Bank: "go", Score: 3, Total: 5, Duration: 5 * time.Second, PlayedAt: start.Add(3 * time.Hour)},
+		{Player: "bob", Bank: "go", Score: 6, Total: 12, Duration: 5 * time.Second, PlayedAt: start.Add(3 * time.Hour)},
+		{Player: "cy", Bank: "go", Score: 5, Total: 5, Duration: 9 * time.Second, PlayedAt: start.Add(3 * time.Hour)},
+	}
+	for i := range scores {
+		if err := board.Save(&scores[i]); err != nil {
+			t.Fatalf("Save() received an error: %s", err.Error())
+		}
+	}
+
+	top, err := board.Top("math", 2)
+	if err != nil {
+		t.Fatalf("Top() received an error: %s", err.Error())
+	}
+	// equal scores are ranked by duration
+	if len(top) != 2 || top[0].ID != scores[2].ID || top[1].ID != scores[1].ID || top[0].Duration != 15*time.Second {
+		t.Errorf("Top(math, 2): got %+v", top)
+	}
+
+	// a perfect sample beats half of the whole bank
+	top, err = board.Top("go", 3)
+	if err != nil {
+		t.Fatalf("Top() received an error: %s", err.Error())
+	}
+	if len(top) != 3 || top[0].Player != "cy" || top[1].Player != "ann" || top[2].Player != "bob" {
+		t.Errorf("Top(go, 3): want cy, ann then bob, got %+v", top)
+	}
+
+	banks, err := board.Banks()
+	if err != nil {
+		t.Fatalf("Banks() received an error: %s", err.Error())
+	}
+	if len(banks) != 2 || banks[0] != "go" {
+		t.Errorf("Banks(): want [go math], got %v", banks)
+	}
+
+	history, err := board.History("ann", "")
+	if err != nil {
+		t.Fatalf("History() received an error: %s", err.Error())
+	}
+	if len(history) != 3 || !history[2].PlayedAt.Equal(scores[3].PlayedAt) {
+		t.Errorf("History(ann): got %+v", history)
+	}
+}
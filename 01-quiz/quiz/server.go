@@ -0,0 +1,292 @@
+package quiz
+
+import (
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"html/template"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed templates/*.gohtml
+var templatesFS embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"letter": func(i int) string { return string(Letter(i)) },
+	"inc":    func(i int) int { return i + 1 },
+}).ParseFS(templatesFS, "templates/*.gohtml"))
+
+const (
+	sessionCookie = "quiz_session"
+	// sessionTTL is how long a finished session stays around for its results
+	// page, an unfinished one is kept for this long after its deadline
+	sessionTTL = time.Hour
+)
+
+// Server serves a quiz over HTTP, one question per page. Every browser gets
+// its own session, identified by a cookie, with a server side deadline
+type Server struct {
+	questions []Question
+	// settings holds the Options given to NewServer, every web session is
+	// configured like it
+	settings Session
+
+	mu       sync.Mutex // guards sessions and settings.rand
+	sessions map[string]*webSession
+}
+
+// webSession is the state of the quiz of one browser
+type webSession struct {
+	questions []Question // with the choices in the order shown
+	report    Report
+	current   int
+	started   time.Time
+	deadline  time.Time // zero without quiz timeout
+	shown     time.Time // when the current question was shown
+}
+
+// NewServer returns a Server asking questions, configured by the same
+// Options as a Session
+func NewServer(questions []Question, opts ...Option) *Server {
+	s := &Server{
+		questions: questions,
+		sessions:  map[string]*webSession{},
+	}
+	s.settings.rand = newRand()
+	for _, opt := range opts {
+		opt(&s.settings)
+	}
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		s.handleStart(w, r)
+	case "/start":
+		s.handleNew(w, r)
+	case "/question":
+		s.handleQuestion(w, r)
+	case "/answer":
+		s.handleAnswer(w, r)
+	case "/results":
+		s.handleResults(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	s.render(w, "start.gohtml", map[string]any{
+		"Questions": len(s.questions),
+		"Timeout":   s.settings.timeout,
+	})
+}
+
+// handleNew starts a new session, replacing the current one of the browser
+func (s *Server) handleNew(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	ws := &webSession{
+		questions: make([]Question, len(s.questions)),
+		report:    Report{Answers: make([]Answer, len(s.questions))},
+		started:   now,
+		shown:     now,
+	}
+	if s.settings.timeout > 0 {
+		ws.deadline = now.Add(s.settings.timeout)
+	}
+
+	s.mu.Lock()
+	s.purge(now)
+	for i, q := range s.questions {
+		ws.questions[i] = q.shuffled(s.settings.rand)
+		ws.report.Answers[i] = Answer{Question: q.Text, Expected: q.Answer}
+	}
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		delete(s.sessions, c.Value)
+	}
+	s.sessions[id] = ws
+	s.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/question", http.StatusSeeOther)
+}
+
+func (s *Server) handleQuestion(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	ws, ok := s.session(r)
+	if !ok {
+		s.mu.Unlock()
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	if ws.finished(time.Now()) {
+		s.mu.Unlock()
+		http.Redirect(w, r, "/results", http.StatusSeeOther)
+		return
+	}
+
+	q := ws.questions[ws.current]
+	data := map[string]any{
+		"Number":   ws.current + 1,
+		"Total":    len(ws.questions),
+		"Question": q,
+		"Type":     q.kind(),
+	}
+	if !ws.deadline.IsZero() {
+		data["Remaining"] = time.Until(ws.deadline).Round(time.Second)
+	}
+	s.mu.Unlock()
+
+	s.render(w, "question.gohtml", data)
+}
+
+func (s *Server) handleAnswer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/question", http.StatusSeeOther)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid answer", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ws, ok := s.session(r)
+	if !ok {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	now := time.Now()
+	if !ws.finished(now) {
+		q := ws.questions[ws.current]
+		answer := &ws.report.Answers[ws.current]
+		given := strings.Join(r.PostForm["answer"], ",")
+
+		answer.Answered = true
+		answer.Given = strings.TrimSpace(given)
+		answer.Took = now.Sub(ws.shown)
+		if timeout := s.settings.timeoutOf(q); timeout > 0 && answer.Took > timeout {
+			// too late for this question, the answer doesn't count
+			answer.TimedOut = true
+		} else {
+			answer.Points = q.Grade(given)
+			answer.Correct = answer.Points == 1
+		}
+		// the time of the next question runs from now, reloading its page
+		// doesn't restart it
+		ws.current++
+		ws.shown = now
+	}
+
+	http.Redirect(w, r, "/question", http.StatusSeeOther)
+}
+
+func (s *Server) handleResults(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	ws, ok := s.session(r)
+	if !ok {
+		s.mu.Unlock()
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	if !ws.finished(time.Now()) {
+		s.mu.Unlock()
+		http.Redirect(w, r, "/question", http.StatusSeeOther)
+		return
+	}
+
+	report := ws.report
+	report.Answers = append([]Answer(nil), ws.report.Answers...)
+	for i := range report.Answers {
+		report.Answers[i].Took = report.Answers[i].Took.Round(time.Second)
+	}
+	s.mu.Unlock()
+
+	s.render(w, "results.gohtml", map[string]any{
+		"TimedOut": report.TimedOut,
+		"Score":    math.Round(report.Score()*100) / 100,
+		"Total":    report.Total(),
+		"Answers":  report.Answers,
+	})
+}
+
+// session returns the session of the browser sending r, s.mu must be held
+func (s *Server) session(r *http.Request) (*webSession, bool) {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil, false
+	}
+	ws, ok := s.sessions[c.Value]
+	return ws, ok
+}
+
+// purge forgets the sessions over for more than sessionTTL, s.mu must be held
+func (s *Server) purge(now time.Time) {
+	for id, ws := range s.sessions {
+		end := ws.started
+		if !ws.deadline.IsZero() {
+			end = ws.deadline
+		}
+		if now.Sub(end) > sessionTTL {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// finished reports whether every question was answered or the deadline is
+// passed, marking the remaining questions as timed out in the latter case
+func (ws *webSession) finished(now time.Time) bool {
+	if ws.current >= len(ws.questions) {
+		return true
+	}
+	if ws.deadline.IsZero() || now.Before(ws.deadline) {
+		return false
+	}
+
+	ws.report.TimedOut = true
+	for i := ws.current; i < len(ws.questions); i++ {
+		ws.report.Answers[i].TimedOut = true
+	}
+	ws.current = len(ws.questions)
+	return true
+}
+
+func (s *Server) render(w http.ResponseWriter, name string, data any) {
+	if err := templates.ExecuteTemplate(w, name, data); err != nil {
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+	}
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
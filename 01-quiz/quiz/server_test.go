@@ -0,0 +1,88 @@
+package quiz
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newClient returns a client keeping its own session cookie
+func newClient(t *testing.T) *http.Client {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("cookiejar.New() received an error: %s", err.Error())
+	}
+	return &http.Client{Jar: jar}
+}
+
+func post(t *testing.T, c *http.Client, u string, form url.Values) string {
+	resp, err := c.PostForm(u, form)
+	if err != nil {
+		t.Fatalf("POST %s received an error: %s", u, err.Error())
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return string(b)
+}
+
+func TestServer(t *testing.T) {
+	questions := []Question{{Text: "1+1", Answer: "2"}, {Text: "2+2", Answer: "4"}}
+	server := httptest.NewServer(NewServer(questions, OptionTimeout(time.Minute)))
+	defer server.Close()
+
+	alice, bob := newClient(t), newClient(t)
+	post(t, alice, server.URL+"/start", nil)
+	post(t, bob, server.URL+"/start", nil)
+
+	// answers of concurrent users don't mix
+	post(t, alice, server.URL+"/answer", url.Values{"answer": {"2"}})
+	post(t, bob, server.URL+"/answer", url.Values{"answer": {"3"}})
+	if page := post(t, alice, server.URL+"/answer", url.Values{"answer": {"4"}}); !strings.Contains(page, "Result: 2/2") {
+		t.Errorf("alice results: want 2/2, got %s", page)
+	}
+	if page := post(t, bob, server.URL+"/answer", url.Values{"answer": {"4"}}); !strings.Contains(page, "Result: 1/2") {
+		t.Errorf("bob results: want 1/2, got %s", page)
+	}
+}
+
+func TestServer_Deadline(t *testing.T) {
+	questions := []Question{{Text: "1+1", Answer: "2"}, {Text: "2+2", Answer: "4"}}
+	server := httptest.NewServer(NewServer(questions, OptionTimeout(20*time.Millisecond)))
+	defer server.Close()
+
+	c := newClient(t)
+	post(t, c, server.URL+"/start", nil)
+	time.Sleep(40 * time.Millisecond)
+
+	page := post(t, c, server.URL+"/answer", url.Values{"answer": {"2"}})
+	if !strings.Contains(page, "The time is over") || !strings.Contains(page, "Result: 0/2") {
+		t.Errorf("results after the deadline: want 0/2 and the time over, got %s", page)
+	}
+}
+
+func TestServer_QuestionTimeout(t *testing.T) {
+	questions := []Question{{Text: "1+1", Answer: "2"}, {Text: "2+2", Answer: "4"}}
+	server := httptest.NewServer(NewServer(questions, OptionQuestionTimeout(20*time.Millisecond)))
+	defer server.Close()
+
+	c := newClient(t)
+	post(t, c, server.URL+"/start", nil)
+	time.Sleep(40 * time.Millisecond)
+
+	// reloading the question doesn't give more time
+	resp, err := c.Get(server.URL + "/question")
+	if err != nil {
+		t.Fatalf("GET /question received an error: %s", err.Error())
+	}
+	resp.Body.Close()
+	post(t, c, server.URL+"/answer", url.Values{"answer": {"2"}})
+	page := post(t, c, server.URL+"/answer", url.Values{"answer": {"4"}})
+	if !strings.Contains(page, "Result: 1/2") {
+		t.Errorf("results with the first question late: want 1/2, got %s", page)
+	}
+}
@@ -0,0 +1,210 @@
+package quiz
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Session runs a list of questions against an input and an output, keeping
+// its own report so several sessions can live side by side. A Session is
+// meant to be run once
+type Session struct {
+	questions []Question
+	in        *bufio.Scanner
+	out       io.Writer
+
+	timeout         time.Duration
+	questionTimeout time.Duration
+	rand            *rand.Rand
+}
+
+// Option configures a Session
+type Option func(*Session)
+
+// OptionTimeout bounds the time of the whole quiz
+func OptionTimeout(d time.Duration) Option {
+	return func(s *Session) {
+		s.timeout = d
+	}
+}
+
+// OptionQuestionTimeout bounds the time of every question that doesn't
+// declare its own timeout
+func OptionQuestionTimeout(d time.Duration) Option {
+	return func(s *Session) {
+		s.questionTimeout = d
+	}
+}
+
+// OptionRand sets the random source used to shuffle the choices of the
+// questions, use it with a fixed seed for reproducible runs
+func OptionRand(rng *rand.Rand) Option {
+	return func(s *Session) {
+		s.rand = rng
+	}
+}
+
+// NewSession returns a Session asking questions on out and reading the
+// answers from in, one line per answer
+func NewSession(questions []Question, in io.Reader, out io.Writer, opts ...Option) *Session {
+	s := &Session{
+		questions: questions,
+		in:        bufio.NewScanner(in),
+		out:       out,
+		rand:      newRand(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Result is the outcome of a Session started in the background
+type Result struct {
+	Report Report
+	Err    error
+}
+
+// Start runs the session in a new goroutine, see Run. The channel receives
+// the Result once the quiz is over and is then closed
+func (s *Session) Start(ctx context.Context) <-chan Result {
+	results := make(chan Result, 1)
+	go func() {
+		defer close(results)
+		report, err := s.Run(ctx)
+		results <- Result{Report: report, Err: err}
+	}()
+	return results
+}
+
+// Run asks every question in order and returns the report of the run.
+//
+// A question that isn't answered within its timeout counts as wrong and the
+// quiz moves on. When ctx deadline or the quiz timeout is reached the
+// remaining questions are reported as timed out, when ctx is canceled the
+// quiz stops and ctx error is returned with the report so far. Running out
+// of input is not an error, the remaining questions are just left
+// unanswered, while an input error ends the quiz and is returned with the
+// report so far.
+func (s *Session) Run(ctx context.Context) (Report, error) {
+	report := Report{Answers: make([]Answer, len(s.questions))}
+	for i, q := range s.questions {
+		report.Answers[i] = Answer{Question: q.Text, Expected: q.Answer}
+	}
+
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	// canceled when the quiz is over to release the input reader
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	lines := s.readLines(ctx)
+
+	for i, q := range s.questions {
+		answer := &report.Answers[i]
+		q = q.shuffled(s.rand)
+
+		// display one question at a time
+		fmt.Fprintf(s.out, "%d. %s\n", i+1, q.Prompt())
+
+		questionCtx, cancelQuestion := ctx, context.CancelFunc(func() {})
+		if timeout := s.timeoutOf(q); timeout > 0 {
+			questionCtx, cancelQuestion = context.WithTimeout(ctx, timeout)
+		}
+
+		start := time.Now()
+		var (
+			l        line
+			received bool
+		)
+		select {
+		case l = <-lines:
+			received = true
+		case <-questionCtx.Done():
+		}
+		cancelQuestion()
+		answer.Took = time.Since(start)
+
+		switch {
+		case received && l.err == io.EOF:
+			return report, nil
+		case received && l.err != nil:
+			return report, fmt.Errorf("reading answer: %w", l.err)
+		case received:
+			answer.Answered = true
+			answer.Given = strings.TrimSpace(l.text)
+			answer.Points = q.Grade(l.text)
+			answer.Correct = answer.Points == 1
+		case ctx.Err() == nil:
+			fmt.Fprintln(s.out, "time's up for this question")
+			answer.TimedOut = true
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			report.TimedOut = true
+			for j := i; j < len(report.Answers); j++ {
+				report.Answers[j].TimedOut = true
+			}
+			return report, nil
+		default:
+			return report, ctx.Err()
+		}
+	}
+	return report, nil
+}
+
+// line is one line of input, the last one read holds io.EOF or the error
+// that stopped the input
+type line struct {
+	text string
+	err  error
+}
+
+// readLines reads the input in a new goroutine so waiting for an answer can
+// be raced against ctx. The goroutine returns once ctx is done, but only
+// after the read it is blocked in returns: there is no way to interrupt a
+// read from a terminal
+func (s *Session) readLines(ctx context.Context) <-chan line {
+	lines := make(chan line)
+	go func() {
+		send := func(l line) bool {
+			select {
+			case lines <- l:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for s.in.Scan() {
+			if !send(line{text: s.in.Text()}) {
+				return
+			}
+		}
+		err := s.in.Err()
+		if err == nil {
+			err = io.EOF
+		}
+		send(line{err: err})
+	}()
+	return lines
+}
+
+func (s *Session) timeoutOf(q Question) time.Duration {
+	if q.Timeout > 0 {
+		return q.Timeout
+	}
+	return s.questionTimeout
+}
+
+// newRand returns a random source seeded with the current time
+func newRand() *rand.Rand {
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
@@ -0,0 +1,159 @@
+package quiz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSession_Run(t *testing.T) {
+	questions, err := NewCSVSource(strings.NewReader("5+5,10\n1+1,2\n8+3,11\n")).Questions()
+	if err != nil {
+		t.Fatalf("Questions() received an error: %s", err.Error())
+	}
+
+	var out bytes.Buffer
+	s := NewSession(questions, strings.NewReader("10\n3\n 11 \n"), &out)
+	report, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() received an error: %s", err.Error())
+	}
+
+	if correct, total := report.Correct(), report.Total(); correct != 2 || total != 3 {
+		t.Errorf("report: want 2/3, got %d/%d", correct, total)
+	}
+	if a := report.Answers[1]; a.Given != "3" || a.Expected != "2" || a.Correct {
+		t.Errorf("report.Answers[1]: want wrong answer 3 for 2, got %+v", a)
+	}
+	if !strings.Contains(out.String(), "3. 8+3?") {
+		t.Errorf("output: want the third question to be asked, got %q", out.String())
+	}
+}
+
+func TestSession_RunOutOfInput(t *testing.T) {
+	questions := []Question{{Text: "1+1", Answer: "2"}, {Text: "2+2", Answer: "4"}}
+
+	s := NewSession(questions, strings.NewReader("2"), &bytes.Buffer{})
+	report, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() received an error: %s", err.Error())
+	}
+
+	if correct, total := report.Correct(), report.Total(); correct != 1 || total != 2 {
+		t.Errorf("report: want 1/2, got %d/%d", correct, total)
+	}
+}
+
+func TestSession_RunTimeouts(t *testing.T) {
+	questions := []Question{
+		{Text: "1+1", Answer: "2", Timeout: 10 * time.Millisecond},
+		{Text: "2+2", Answer: "4"},
+		{Text: "3+3", Answer: "6"},
+	}
+
+	// the input never delivers an answer, so the first question times out on
+	// its own limit and the second one on the quiz limit
+	in, w := io.Pipe()
+	defer w.Close()
+
+	s := NewSession(questions, in, &bytes.Buffer{}, OptionTimeout(50*time.Millisecond))
+	report, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() received an error: %s", err.Error())
+	}
+
+	if !report.TimedOut {
+		t.Errorf("report.TimedOut: want true, got false")
+	}
+	for i, a := range report.Answers {
+		if !a.TimedOut || a.Correct {
+			t.Errorf("report.Answers[%d]: want timed out, got %+v", i, a)
+		}
+	}
+}
+
+func TestSourceFor(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+	}{
+		{name: "problems.csv", data: "5+5,10\n"},
+		{name: "header.csv", data: "answer,question\n10,5+5\n"},
+		{name: "problems.json", data: `[{"question": "5+5", "answer": "10"}]`},
+		{name: "problems.yaml", data: "- question: 5+5\n  answer: 10\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			questions, err := SourceFor(c.name, strings.NewReader(c.data)).Questions()
+			if err != nil {
+				t.Fatalf("Questions() received an error: %s", err.Error())
+			}
+			if len(questions) != 1 || !reflect.DeepEqual(questions[0], Question{Text: "5+5", Answer: "10"}) {
+				t.Errorf("Questions(): want [{5+5 10}], got %v", questions)
+			}
+		})
+	}
+}
+
+// errReader returns its lines then fails
+type errReader struct {
+	data string
+	err  error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestSession_RunInputError(t *testing.T) {
+	questions := []Question{{Text: "1+1", Answer: "2"}, {Text: "2+2", Answer: "4"}}
+	readErr := errors.New("broken terminal")
+
+	s := NewSession(questions, &errReader{data: "2\n", err: readErr}, &bytes.Buffer{})
+	report, err := s.Run(context.Background())
+	if !errors.Is(err, readErr) {
+		t.Errorf("Run(): want the input error, got %v", err)
+	}
+	if correct := report.Correct(); correct != 1 {
+		t.Errorf("report: want the first answer kept, got %d correct", correct)
+	}
+}
+
+func TestSession_Start(t *testing.T) {
+	questions := []Question{{Text: "1+1", Answer: "2"}, {Text: "2+2", Answer: "4"}}
+
+	// the answer to the first question only comes after cancellation, which
+	// must end the quiz without waiting for it
+	in, w := io.Pipe()
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := NewSession(questions, in, &bytes.Buffer{}).Start(ctx)
+
+	go w.Write([]byte("2\n"))
+	time.Sleep(10 * time.Millisecond) // let the first answer in
+	cancel()
+
+	select {
+	case result := <-results:
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("Result.Err: want context.Canceled, got %v", result.Err)
+		}
+		if result.Report.Correct() != 1 || result.Report.TimedOut {
+			t.Errorf("Result.Report: want 1 correct answer, got %+v", result.Report)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Start(): no result after cancellation")
+	}
+}
@@ -0,0 +1,289 @@
+package quiz
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The SM-2 constants, see https://super-memory.com/english/ol/sm2.htm
+const (
+	defaultEase = 2.5
+	minEase     = 1.3
+	// matureInterval is the interval in days from which a card is mastered
+	matureInterval = 21
+	// slowAnswer is the time after which a correct answer is considered slow
+	// when the question has no timeout
+	slowAnswer = 10 * time.Second
+)
+
+// Attempt is one answer given to a question while studying
+type Attempt struct {
+	At      time.Time     `json:"at"`
+	Points  float64       `json:"points"`
+	Took    time.Duration `json:"took"`
+	Quality int           `json:"quality"`
+}
+
+// Card is the spaced repetition state of one question
+type Card struct {
+	ID          string    `json:"id"`
+	Question    string    `json:"question"`
+	Ease        float64   `json:"ease"`
+	Interval    int       `json:"interval"` // in days
+	Repetitions int       `json:"repetitions"`
+	Due         time.Time `json:"due"`
+	History     []Attempt `json:"history"`
+}
+
+// Mastered reports whether the card came back right often enough to be
+// scheduled weeks away
+func (c *Card) Mastered() bool {
+	return c.Interval >= matureInterval
+}
+
+// review updates the schedule of the card with an answer of the given SM-2
+// quality, from 0 (blackout) to 5 (perfect)
+func (c *Card) review(quality int, now time.Time) {
+	if c.Ease == 0 {
+		c.Ease = defaultEase
+	}
+
+	if quality < 3 {
+		// start learning the card again from scratch
+		c.Repetitions = 0
+		c.Interval = 1
+	} else {
+		switch c.Repetitions {
+		case 0:
+			c.Interval = 1
+		case 1:
+			c.Interval = 6
+		default:
+			c.Interval = int(math.Round(float64(c.Interval) * c.Ease))
+		}
+		c.Repetitions++
+	}
+
+	q := float64(5 - quality)
+	c.Ease = math.Max(minEase, c.Ease+0.1-q*(0.08+q*0.02))
+	c.Due = now.AddDate(0, 0, c.Interval)
+}
+
+// Quality grades an answer on the SM-2 scale: wrong or timed out answers get
+// 1, partially right ones 2 and right ones from 3 to 5 depending on how fast
+// they came compared to the question timeout
+func Quality(a Answer, timeout time.Duration) int {
+	switch {
+	case a.TimedOut || a.Points == 0:
+		return 1
+	case a.Points < 1:
+		return 2
+	}
+
+	slow := slowAnswer
+	if timeout > 0 {
+		slow = timeout / 2
+	}
+	switch {
+	case a.Took > slow:
+		return 3
+	case a.Took > slow/2:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// QuestionID identifies a question across runs from its text
+func QuestionID(q Question) string {
+	sum := sha1.Sum([]byte(strings.ToLower(strings.TrimSpace(q.Text))))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Progress is the study history of a user, persisted in a JSON file
+type Progress struct {
+	path  string
+	Cards map[string]*Card `json:"cards"`
+}
+
+// OpenProgress loads the progress stored at path, a missing file is an empty
+// progress that Save will create
+func OpenProgress(path string) (*Progress, error) {
+	p := &Progress{path: path, Cards: map[string]*Card{}}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, err
+	}
+	if p.Cards == nil {
+		p.Cards = map[string]*Card{}
+	}
+	return p, nil
+}
+
+// Save writes the progress back to its file, through a temporary file so a
+// crash never leaves a truncated progress behind
+func (p *Progress) Save() error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p.path), ".progress-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p.path)
+}
+
+// Card returns the card of q, nil when q was never studied
+func (p *Progress) Card(q Question) *Card {
+	return p.Cards[QuestionID(q)]
+}
+
+// Due returns up to limit questions to study at now: the overdue ones first,
+// the most overdue first, then the questions never studied in bank order.
+// Zero or a negative limit returns all of them
+func (p *Progress) Due(questions []Question, now time.Time, limit int) []Question {
+	var due, fresh []Question
+	for _, q := range questions {
+		card := p.Card(q)
+		switch {
+		case card == nil:
+			fresh = append(fresh, q)
+		case !card.Due.After(now):
+			due = append(due, q)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return p.Card(due[i]).Due.Before(p.Card(due[j]).Due)
+	})
+
+	due = append(due, fresh...)
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due
+}
+
+// Record schedules q again from the answer given at now
+func (p *Progress) Record(q Question, a Answer, timeout time.Duration, now time.Time) *Card {
+	id := QuestionID(q)
+	card, ok := p.Cards[id]
+	if !ok {
+		card = &Card{ID: id, Ease: defaultEase}
+		p.Cards[id] = card
+	}
+	card.Question = q.Text
+
+	quality := Quality(a, timeout)
+	card.review(quality, now)
+	card.History = append(card.History, Attempt{
+		At:      now,
+		Points:  a.Points,
+		Took:    a.Took,
+		Quality: quality,
+	})
+	return card
+}
+
+// untagged is the category of questions without tags
+const untagged = "untagged"
+
+// CategoryStats sums up the progress on the questions of one tag
+type CategoryStats struct {
+	Tag       string
+	Questions int
+	Studied   int
+	Mastered  int
+	Due       int
+	Attempts  int
+	Points    float64
+}
+
+// Mastery returns the share of the questions that are mastered
+func (s CategoryStats) Mastery() float64 {
+	if s.Questions == 0 {
+		return 0
+	}
+	return float64(s.Mastered) / float64(s.Questions)
+}
+
+// Accuracy returns the average points earned per attempt
+func (s CategoryStats) Accuracy() float64 {
+	if s.Attempts == 0 {
+		return 0
+	}
+	return s.Points / float64(s.Attempts)
+}
+
+// Stats returns the progress on questions per tag sorted by tag, a question
+// counts in every category it is tagged with
+func (p *Progress) Stats(questions []Question, now time.Time) []CategoryStats {
+	byTag := map[string]*CategoryStats{}
+	for _, q := range questions {
+		tags := q.Tags
+		if len(tags) == 0 {
+			tags = []string{untagged}
+		}
+
+		card := p.Card(q)
+		for _, tag := range tags {
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			s, ok := byTag[tag]
+			if !ok {
+				s = &CategoryStats{Tag: tag}
+				byTag[tag] = s
+			}
+
+			s.Questions++
+			if card == nil {
+				continue
+			}
+			s.Studied++
+			if card.Mastered() {
+				s.Mastered++
+			}
+			if !card.Due.After(now) {
+				s.Due++
+			}
+			for _, a := range card.History {
+				s.Attempts++
+				s.Points += a.Points
+			}
+		}
+	}
+
+	stats := make([]CategoryStats, 0, len(byTag))
+	for _, s := range byTag {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Tag < stats[j].Tag
+	})
+	return stats
+}
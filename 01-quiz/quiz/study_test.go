@@ -0,0 +1,91 @@
+package quiz
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProgress_Record(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	q := Question{Text: "5+5", Answer: "10"}
+	p := &Progress{Cards: map[string]*Card{}}
+
+	// fast right answers space the reviews out 1, 6 then 6*2.7 days
+	for _, want := range []int{1, 6, 16} {
+		card := p.Record(q, Answer{Correct: true, Points: 1, Took: time.Second}, 0, now)
+		if card.Interval != want {
+			t.Errorf("Interval: want %d, got %d", want, card.Interval)
+		}
+	}
+
+	// a wrong answer brings the question back the next day
+	card := p.Record(q, Answer{Took: time.Second}, 0, now)
+	if card.Interval != 1 || card.Repetitions != 0 || !card.Due.Equal(now.AddDate(0, 0, 1)) {
+		t.Errorf("card after a wrong answer: got %+v", card)
+	}
+	if card.Ease < minEase || card.Ease >= defaultEase {
+		t.Errorf("Ease: want lowered after a wrong answer, got %v", card.Ease)
+	}
+	if len(card.History) != 4 {
+		t.Errorf("History: want 4 attempts, got %d", len(card.History))
+	}
+}
+
+func TestQuality(t *testing.T) {
+	cases := []struct {
+		a       Answer
+		timeout time.Duration
+		quality int
+	}{
+		{a: Answer{TimedOut: true}, quality: 1},
+		{a: Answer{Points: 0.5, Took: time.Second}, quality: 2},
+		{a: Answer{Points: 1, Took: 20 * time.Second}, quality: 3},
+		{a: Answer{Points: 1, Took: 6 * time.Second}, quality: 4},
+		{a: Answer{Points: 1, Took: time.Second}, quality: 5},
+		{a: Answer{Points: 1, Took: 3 * time.Second}, timeout: 4 * time.Second, quality: 3},
+	}
+
+	for _, c := range cases {
+		if got := Quality(c.a, c.timeout); got != c.quality {
+			t.Errorf("Quality(%+v, %v) == %d, expected %d", c.a, c.timeout, got, c.quality)
+		}
+	}
+}
+
+func TestProgress_DueAndSave(t *testing.T) {
+	now := time.Now()
+	questions := []Question{
+		{Text: "1+1", Answer: "2", Tags: []string{"math"}},
+		{Text: "2+2", Answer: "4", Tags: []string{"math"}},
+		{Text: "Go was created at", Answer: "google"},
+	}
+
+	path := filepath.Join(t.TempDir(), "progress.json")
+	p, err := OpenProgress(path)
+	if err != nil {
+		t.Fatalf("OpenProgress() received an error: %s", err.Error())
+	}
+	p.Record(questions[0], Answer{Points: 1, Took: time.Second}, 0, now)
+	p.Record(questions[1], Answer{}, 0, now.AddDate(0, 0, -2))
+	if err := p.Save(); err != nil {
+		t.Fatalf("Save() received an error: %s", err.Error())
+	}
+
+	p, err = OpenProgress(path)
+	if err != nil {
+		t.Fatalf("OpenProgress() received an error: %s", err.Error())
+	}
+
+	// the overdue question first, then the new one, the one answered today
+	// isn't due yet
+	due := p.Due(questions, now, 0)
+	if len(due) != 2 || due[0].Text != "2+2" || due[1].Text != "Go was created at" {
+		t.Errorf("Due(): got %v", due)
+	}
+
+	stats := p.Stats(questions, now)
+	if len(stats) != 2 || stats[0].Tag != "math" || stats[0].Studied != 2 || stats[0].Due != 1 || stats[0].Accuracy() != 0.5 {
+		t.Errorf("Stats(): got %+v", stats)
+	}
+}
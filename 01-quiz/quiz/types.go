@@ -0,0 +1,205 @@
+package quiz
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// The list of question types that can be assigned to a Question
+const (
+	TypeText   = "text"   // free text answer checked by the question Matcher
+	TypeChoice = "choice" // pick the one correct answer among the choices
+	TypeMulti  = "multi"  // select all that apply, answers are "|" separated
+	TypeBool   = "bool"   // true or false
+)
+
+// maxChoices is the number of letters available to label choices
+const maxChoices = 26
+
+// kind returns the type of q, questions without a type are free text
+func (q Question) kind() string {
+	if q.Type == "" {
+		return TypeText
+	}
+	return strings.ToLower(q.Type)
+}
+
+// shuffled returns a copy of q with its choices in a random order
+func (q Question) shuffled(rng *rand.Rand) Question {
+	if len(q.Choices) == 0 {
+		return q
+	}
+	choices := append([]string(nil), q.Choices...)
+	rng.Shuffle(len(choices), func(i, j int) {
+		choices[i], choices[j] = choices[j], choices[i]
+	})
+	q.Choices = choices
+	return q
+}
+
+// Prompt returns the text shown to the user for q, with the choices
+// labelled by letter for the choice types
+func (q Question) Prompt() string {
+	var sb strings.Builder
+	sb.WriteString(q.Text)
+	sb.WriteString("?")
+
+	switch q.kind() {
+	case TypeBool:
+		sb.WriteString(" (true/false)")
+	case TypeMulti:
+		sb.WriteString(" (select all that apply, e.g. a,c)")
+	}
+	for i, choice := range q.Choices {
+		fmt.Fprintf(&sb, "\n   %c) %s", Letter(i), choice)
+	}
+	return sb.String()
+}
+
+// Letter returns the label of the i-th choice
+func Letter(i int) rune {
+	return rune('a' + i)
+}
+
+// Grade returns the points earned by answer, from 0 to 1.
+//
+// Choices can be answered by their letter or their text. A multi answer
+// lists its choices separated by commas or spaces and earns partial credit:
+// every right choice adds a share of the point, every wrong one removes a
+// share.
+func (q Question) Grade(answer string) float64 {
+	switch q.kind() {
+	case TypeBool:
+		got, ok := parseBool(answer)
+		want, _ := parseBool(q.Answer)
+		return points(ok && got == want)
+
+	case TypeChoice:
+		got, ok := q.choice(answer)
+		return points(ok && strings.EqualFold(got, strings.TrimSpace(q.Answer)))
+
+	case TypeMulti:
+		want := map[string]bool{}
+		for _, a := range strings.Split(q.Answer, "|") {
+			want[strings.ToLower(strings.TrimSpace(a))] = true
+		}
+
+		picked := map[string]bool{}
+		for _, field := range q.splitAnswer(answer) {
+			got, ok := q.choice(field)
+			if !ok {
+				continue
+			}
+			picked[strings.ToLower(got)] = true
+		}
+
+		score := 0
+		for choice := range picked {
+			if want[choice] {
+				score++
+			} else {
+				score--
+			}
+		}
+		if score <= 0 {
+			return 0
+		}
+		return float64(score) / float64(len(want))
+
+	default:
+		return points(q.Check(answer))
+	}
+}
+
+// splitAnswer splits a multi answer into the choices it lists. Commas
+// separate choices when present, otherwise spaces do unless the whole answer
+// is a choice text
+func (q Question) splitAnswer(answer string) []string {
+	if strings.Contains(answer, ",") {
+		return strings.Split(answer, ",")
+	}
+	if q.hasChoice(answer) {
+		return []string{answer}
+	}
+	return strings.Fields(answer)
+}
+
+// choice resolves answer, a letter or the text of a choice, to the choice
+func (q Question) choice(answer string) (string, bool) {
+	answer = strings.TrimSpace(answer)
+	if len(answer) == 1 {
+		if i := int(strings.ToLower(answer)[0] - 'a'); i >= 0 && i < len(q.Choices) {
+			return strings.TrimSpace(q.Choices[i]), true
+		}
+	}
+	for _, choice := range q.Choices {
+		if strings.EqualFold(strings.TrimSpace(choice), answer) {
+			return strings.TrimSpace(choice), true
+		}
+	}
+	return "", false
+}
+
+// validateType reports the type specific options of q that are invalid
+func (q Question) validateType() error {
+	switch q.kind() {
+	case TypeText:
+		if len(q.Choices) > 0 {
+			return fmt.Errorf("choices are only allowed for %s and %s questions", TypeChoice, TypeMulti)
+		}
+		_, err := q.Matcher()
+		return err
+
+	case TypeBool:
+		if _, ok := parseBool(q.Answer); !ok {
+			return fmt.Errorf("answer %q of a %s question must be true or false", q.Answer, TypeBool)
+		}
+		return nil
+
+	case TypeChoice, TypeMulti:
+		if len(q.Choices) < 2 {
+			return fmt.Errorf("a %s question needs at least 2 choices", q.kind())
+		}
+		if len(q.Choices) > maxChoices {
+			return fmt.Errorf("a %s question can have at most %d choices", q.kind(), maxChoices)
+		}
+		answers := []string{q.Answer}
+		if q.kind() == TypeMulti {
+			answers = strings.Split(q.Answer, "|")
+		}
+		for _, a := range answers {
+			if !q.hasChoice(a) {
+				return fmt.Errorf("answer %q is not one of the choices", strings.TrimSpace(a))
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown question type %q", q.Type)
+}
+
+func (q Question) hasChoice(answer string) bool {
+	for _, choice := range q.Choices {
+		if strings.EqualFold(strings.TrimSpace(choice), strings.TrimSpace(answer)) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseBool(s string) (value, ok bool) {
+	switch normalize(s) {
+	case "t", "true", "y", "yes":
+		return true, true
+	case "f", "false", "n", "no":
+		return false, true
+	}
+	return false, false
+}
+
+func points(correct bool) float64 {
+	if correct {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+package quiz
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestQuestion_Grade(t *testing.T) {
+	choice := Question{Text: "Go was created at", Type: TypeChoice, Choices: []string{"Google", "Apple", "New York"}, Answer: "Google"}
+	multi := Question{Text: "Pick the primes", Type: TypeMulti, Choices: []string{"2", "4", "5", "9"}, Answer: "2|5"}
+	boolean := Question{Text: "Go has generics", Type: TypeBool, Answer: "true"}
+
+	cases := []struct {
+		name   string
+		q      Question
+		answer string
+		points float64
+	}{
+		{name: "choice: letter", q: choice, answer: "A", points: 1},
+		{name: "choice: text", q: choice, answer: "google", points: 1},
+		{name: "choice: wrong", q: choice, answer: "b", points: 0},
+		{name: "choice: not a choice", q: choice, answer: "z", points: 0},
+		{name: "multi: all", q: multi, answer: "a,c", points: 1},
+		{name: "multi: text", q: multi, answer: "5 2", points: 1},
+		{name: "multi: partial", q: multi, answer: "a", points: 0.5},
+		{name: "multi: wrong pick", q: multi, answer: "a,b", points: 0},
+		{name: "multi: duplicates", q: multi, answer: "a,a,2", points: 0.5},
+		{name: "bool", q: boolean, answer: "yes", points: 1},
+		{name: "bool: wrong", q: boolean, answer: "F", points: 0},
+		{name: "text", q: Question{Answer: "10"}, answer: "10", points: 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.q.Grade(c.answer); got != c.points {
+				t.Errorf("Grade(%q) == %v, expected %v", c.answer, got, c.points)
+			}
+		})
+	}
+}
+
+func TestQuestion_shuffled(t *testing.T) {
+	q := Question{Type: TypeChoice, Choices: []string{"a", "b", "c", "d", "e", "f"}, Answer: "a"}
+
+	s1 := q.shuffled(rand.New(rand.NewSource(1)))
+	s2 := q.shuffled(rand.New(rand.NewSource(1)))
+	if strings.Join(s1.Choices, "") != strings.Join(s2.Choices, "") {
+		t.Errorf("shuffled: want the same order for the same seed, got %v and %v", s1.Choices, s2.Choices)
+	}
+	if strings.Join(q.Choices, "") != "abcdef" {
+		t.Errorf("shuffled: want the original choices untouched, got %v", q.Choices)
+	}
+}
+
+func TestCSVSource_Types(t *testing.T) {
+	data := "question,type,choices,answer\n" +
+		"Go was created at,choice,Google|Apple,Google\n" +
+		"Pick the primes,multi,2|4|5,2|5\n" +
+		"Go has generics,bool,,true\n"
+
+	questions, err := NewCSVSource(strings.NewReader(data)).Questions()
+	if err != nil {
+		t.Fatalf("Questions() received an error: %s", err.Error())
+	}
+	if len(questions) != 3 || len(questions[1].Choices) != 3 || questions[2].Type != TypeBool {
+		t.Errorf("Questions(): got %+v", questions)
+	}
+
+	invalid := []string{
+		"question,type,choices,answer\nGo was created at,choice,Google|Apple,Mozilla\n",
+		"question,type,answer\nGo has generics,bool,maybe\n",
+		"question,type,answer\nGo has generics,essay,yes\n",
+	}
+	for _, data := range invalid {
+		if _, err := NewCSVSource(strings.NewReader(data)).Questions(); err == nil {
+			t.Errorf("Questions(%q): want an error, got nil", data)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aboelkassem/gophercises/quiz/quiz"
+)
+
+const progressFileName = ".quiz_progress.json"
+
+// runStudy asks the questions that are due for review and records how they
+// were answered, so the wrong and slow ones come back sooner
+func runStudy(args []string) {
+	fs := flag.NewFlagSet("study", flag.ExitOnError)
+	var (
+		flagFileName      = fs.String("p", problemsFileName, "The path to the problems file")
+		flagStore         = fs.String("store", progressFileName, "The path to the progress file")
+		flagSample        = fs.Int("n", 20, "The Max number of questions to review, 0 for all the due ones")
+		flagTags          = fs.String("tags", "", "Comma separated tags, study only the questions having one of them")
+		flagQuestionTimer = fs.Duration("qt", 0, "The Max time for each question, 0 for no limit")
+	)
+	fs.Parse(args)
+
+	questions, err := quiz.Load(*flagFileName)
+	if err != nil {
+		fmt.Printf("Failed to load questions %v", err)
+		return
+	}
+
+	progress, err := quiz.OpenProgress(*flagStore)
+	if err != nil {
+		fmt.Printf("Failed to load progress %v", err)
+		return
+	}
+
+	questions = quiz.Select(questions, quiz.FilterTags(splitList(*flagTags)...))
+	due := progress.Due(questions, time.Now(), *flagSample)
+	if len(due) == 0 {
+		fmt.Println("Nothing to review right now, come back later!")
+		return
+	}
+
+	fmt.Printf("%d question(s) to review\n", len(due))
+	session := quiz.NewSession(due, os.Stdin, os.Stdout, quiz.OptionQuestionTimeout(*flagQuestionTimer))
+	report, err := session.Run(context.Background())
+	if err != nil {
+		fmt.Printf("Failed to scan: %v\n", err)
+	}
+
+	now := time.Now()
+	for i, a := range report.Answers {
+		if !a.Answered && !a.TimedOut {
+			continue // out of input, keep the card as it was
+		}
+
+		q := due[i]
+		timeout := q.Timeout
+		if timeout == 0 {
+			timeout = *flagQuestionTimer
+		}
+
+		card := progress.Record(q, a, timeout, now)
+		fmt.Printf("%d. %s: %s, next review in %d day(s)\n", i+1, q.Text, verdict(a), card.Interval)
+	}
+
+	if err := progress.Save(); err != nil {
+		fmt.Printf("Failed to save progress %v\n", err)
+	}
+}
+
+// runStats prints the mastery of every category of a question bank
+func runStats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	var (
+		flagFileName = fs.String("p", problemsFileName, "The path to the problems file")
+		flagStore    = fs.String("store", progressFileName, "The path to the progress file")
+	)
+	fs.Parse(args)
+
+	questions, err := quiz.Load(*flagFileName)
+	if err != nil {
+		fmt.Printf("Failed to load questions %v", err)
+		return
+	}
+
+	progress, err := quiz.OpenProgress(*flagStore)
+	if err != nil {
+		fmt.Printf("Failed to load progress %v", err)
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Category\tQuestions\tStudied\tMastered\tDue\tAccuracy\tMastery")
+	for _, s := range progress.Stats(questions, time.Now()) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%.0f%%\t%.0f%%\n",
+			s.Tag, s.Questions, s.Studied, s.Mastered, s.Due, s.Accuracy()*100, s.Mastery()*100)
+	}
+	tw.Flush()
+}
+
+func verdict(a quiz.Answer) string {
+	switch {
+	case a.Correct:
+		return "correct"
+	case a.TimedOut:
+		return "timed out"
+	case a.Points > 0:
+		return "partially correct"
+	default:
+		return "wrong, expected " + a.Expected
+	}
+}
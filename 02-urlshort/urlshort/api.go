@@ -0,0 +1,397 @@
+package urlshort
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// APIPrefix is the path the API handles its links under
+	APIPrefix = "/api/links"
+	// StatsPrefix is the path the API reports the clicks under
+	StatsPrefix = "/api/stats"
+	// statsDays is how many days of clicks are reported by default
+	statsDays = 30
+
+	base62     = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	slugLength = 7
+	// slugAttempts is how many generated slugs are tried before giving up
+	slugAttempts = 5
+)
+
+// API is an http.Handler managing the links of a Store as JSON:
+//
+//	GET    /api/links          list every link
+//	POST   /api/links          create a link, the path is generated when empty
+//	GET    /api/links/{path}   get a link
+//	PUT    /api/links/{path}   replace the URL and rules of a link
+//	DELETE /api/links/{path}   delete a link
+//	GET    /api/stats          clicks of every link, see Summarize
+//	GET    /api/stats/{path}   clicks of a link
+//
+// The links of a domain are given by the domain query parameter, like
+// /api/links/docs?domain=go.example.com, or by the domain field when
+// created. The stats take the number of days reported and of top referrers
+// from the days and referrers query parameters, with the domain parameter
+// /api/stats reports the links of that domain only. Every request needs an
+// "Authorization: Bearer <token>" header
+type API struct {
+	store  Store
+	clicks ClickLog // nil when the store doesn't record clicks
+	token  string
+
+	mu sync.Mutex // serializes the check for duplicates and the write
+}
+
+// NewAPI returns an API managing the links of store, accepting requests
+// authenticated with token. An empty token refuses every request
+func NewAPI(store Store, token string) *API {
+	clicks, _ := store.(ClickLog)
+	return &API{store: store, clicks: clicks, token: token}
+}
+
+// ServeHTTP implements http.Handler
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !a.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="urlshort"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid token")
+		return
+	}
+
+	if path, ok := strings.CutPrefix(r.URL.Path, StatsPrefix); ok {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		a.stats(w, r, Domain(r.URL.Query().Get("domain")), strings.TrimSuffix(path, "/"))
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, APIPrefix)
+	if path == "" || path == "/" {
+		switch r.Method {
+		case http.MethodGet:
+			a.list(w)
+		case http.MethodPost:
+			a.create(w, r)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	domain := Domain(r.URL.Query().Get("domain"))
+	switch r.Method {
+	case http.MethodGet:
+		a.get(w, domain, path)
+	case http.MethodPut:
+		a.update(w, r, domain, path)
+	case http.MethodDelete:
+		a.delete(w, domain, path)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (a *API) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && a.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1
+}
+
+func (a *API) list(w http.ResponseWriter) {
+	links, err := a.store.List()
+	if err != nil {
+		storeError(w, "list", "failed to list links", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, links)
+}
+
+func (a *API) create(w http.ResponseWriter, r *http.Request) {
+	var link LinkData
+	if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	link.Domain = Domain(link.Domain)
+	if err := ValidateURL(link.URL); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// the routes of the URL are checked against the clean path
+	if link.Path != "" {
+		path, err := cleanPath(link.Path)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		link.Path = path
+	}
+	if err := link.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if link.Path == "" {
+		path, err := a.generatePath(link.Domain)
+		if err != nil {
+			storeError(w, "create", "failed to generate a path", err)
+			return
+		}
+		link.Path = path
+	} else {
+		_, err := a.store.Get(link.Domain, link.Path)
+		if err == nil {
+			writeError(w, http.StatusConflict, fmt.Sprintf("path %q is already taken", link.Path))
+			return
+		}
+		if !errors.Is(err, ErrNotFound) {
+			storeError(w, "create", "failed to look the link up", err)
+			return
+		}
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	link.CreatedAt = &now
+	if err := a.store.Put(link); err != nil {
+		storeError(w, "create", "failed to store the link", err)
+		return
+	}
+	location := APIPrefix + link.Path
+	if link.Domain != "" {
+		location += "?domain=" + url.QueryEscape(link.Domain)
+	}
+	w.Header().Set("Location", location)
+	writeJSON(w, http.StatusCreated, link)
+}
+
+func (a *API) get(w http.ResponseWriter, domain, path string) {
+	link, err := a.store.Get(domain, path)
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, http.StatusNotFound, "link not found")
+		return
+	}
+	if err != nil {
+		storeError(w, "get", "failed to look the link up", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, link)
+}
+
+func (a *API) update(w http.ResponseWriter, r *http.Request, domain, path string) {
+	var link LinkData
+	if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if link.Path != "" && link.Path != path || link.Domain != "" && Domain(link.Domain) != domain {
+		writeError(w, http.StatusBadRequest, "the domain and path of a link can't be changed")
+		return
+	}
+	link.Domain, link.Path = domain, path
+	if err := ValidateURL(link.URL); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := link.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	old, err := a.store.Get(domain, path)
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, http.StatusNotFound, "link not found")
+		return
+	}
+	if err != nil {
+		storeError(w, "update", "failed to look the link up", err)
+		return
+	}
+	link.CreatedAt = old.CreatedAt
+	if err := a.store.Put(link); err != nil {
+		storeError(w, "update", "failed to store the link", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, link)
+}
+
+func (a *API) delete(w http.ResponseWriter, domain, path string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	err := a.store.Delete(domain, path)
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, http.StatusNotFound, "link not found")
+		return
+	}
+	if err != nil {
+		storeError(w, "delete", "failed to delete the link", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) stats(w http.ResponseWriter, r *http.Request, domain, path string) {
+	if a.clicks == nil {
+		writeError(w, http.StatusNotImplemented, "the store doesn't record clicks")
+		return
+	}
+
+	days, err := queryInt(r, "days", statsDays)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	top, err := queryInt(r, "referrers", 10)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	clicks, err := a.clicks.Clicks(domain, path, Since(days, time.Now()))
+	if err != nil {
+		storeError(w, "clicks", "failed to load the clicks", err)
+		return
+	}
+	reports := Summarize(clicks, top)
+	if path == "" {
+		writeJSON(w, http.StatusOK, reports)
+		return
+	}
+	if len(reports) == 0 {
+		reports = append(reports, ClickReport{Domain: domain, Path: path})
+	}
+	writeJSON(w, http.StatusOK, reports[0])
+}
+
+// Since returns the start of the UTC day days-1 days before now, so a report
+// of 1 day covers today
+func Since(days int, now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d-days+1, 0, 0, 0, 0, time.UTC)
+}
+
+func queryInt(r *http.Request, key string, def int) (int, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s %q: expected a positive number", key, v)
+	}
+	return n, nil
+}
+
+// generatePath returns a random base62 path not used yet on domain, a.mu
+// must be held
+func (a *API) generatePath(domain string) (string, error) {
+	for i := 0; i < slugAttempts; i++ {
+		slug, err := NewSlug(slugLength)
+		if err != nil {
+			return "", err
+		}
+		_, err = a.store.Get(domain, "/"+slug)
+		if errors.Is(err, ErrNotFound) {
+			return "/" + slug, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", errors.New("urlshort: no free path found")
+}
+
+// NewSlug returns a random base62 ID of n characters
+func NewSlug(n int) (string, error) {
+	max := big.NewInt(int64(len(base62)))
+	b := make([]byte, n)
+	for i := range b {
+		j, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = base62[j.Int64()]
+	}
+	return string(b), nil
+}
+
+// ValidateURL checks that raw is an absolute http or https URL
+func ValidateURL(raw string) error {
+	if raw == "" {
+		return errors.New("missing url")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid url %q", raw)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid url %q: only http and https urls are allowed", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid url %q: missing host", raw)
+	}
+	return nil
+}
+
+// cleanPath makes a custom path start with a slash and checks that it only
+// uses characters safe in a URL path or a pattern, outside of the API paths
+func cleanPath(path string) (string, error) {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if path == "/" {
+		return "", errors.New("invalid path: it can't be empty")
+	}
+	for _, c := range path[1:] {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_./{}*", c)) {
+			return "", fmt.Errorf("invalid path %q: only letters, digits, '-', '_', '.', '/' and the pattern characters '{', '}', '*' are allowed", path)
+		}
+	}
+	if path == "/api" || strings.HasPrefix(path, "/api/") {
+		return "", fmt.Errorf("invalid path %q: /api is reserved", path)
+	}
+	switch path {
+	case "/healthz", "/readyz", "/metrics":
+		return "", fmt.Errorf("invalid path %q: it is served by the shortener", path)
+	}
+	return path, nil
+}
+
+// storeError answers 500 for a failed store operation, the error itself is
+// only logged
+func storeError(w http.ResponseWriter, op, message string, err error) {
+	metrics.storeErrors.inc(op)
+	slog.Error(message, "op", op, "err", err)
+	writeError(w, http.StatusInternalServerError, message)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
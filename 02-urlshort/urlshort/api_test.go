@@ -0,0 +1,147 @@
+package urlshort
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func apiRequest(api http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestAPI(t *testing.T) {
+	store := NewMemoryStore(
+		LinkData{Path: "/taken", URL: "https://example.com"},
+		LinkData{Path: "/issue/{id}", URL: "https://tracker/browse/PROJ-{id}"},
+	)
+	api := NewAPI(store, "secret")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		status int
+	}{
+		{"no token", http.MethodGet, "/api/links", "", "", http.StatusUnauthorized},
+		{"wrong token", http.MethodGet, "/api/links", "nope", "", http.StatusUnauthorized},
+		{"list", http.MethodGet, "/api/links", "secret", "", http.StatusOK},
+		{"custom path", http.MethodPost, "/api/links", "secret", `{"path": "docs", "url": "https://go.dev/doc"}`, http.StatusCreated},
+		{"duplicate", http.MethodPost, "/api/links", "secret", `{"path": "/taken", "url": "https://go.dev"}`, http.StatusConflict},
+		{"relative url", http.MethodPost, "/api/links", "secret", `{"url": "/somewhere"}`, http.StatusBadRequest},
+		{"ftp url", http.MethodPost, "/api/links", "secret", `{"url": "ftp://example.com"}`, http.StatusBadRequest},
+		{"invalid path", http.MethodPost, "/api/links", "secret", `{"path": "a b", "url": "https://go.dev"}`, http.StatusBadRequest},
+		{"unclean route", http.MethodPost, "/api/links", "secret", `{"path": "bug/{id}", "url": "https://tracker/PROJ-{nope}"}`, http.StatusBadRequest},
+		{"update route", http.MethodPut, "/api/links/issue/{id}", "secret", `{"url": "https://tracker/PROJ-{nope}"}`, http.StatusBadRequest},
+		{"reserved path", http.MethodPost, "/api/links", "secret", `{"path": "/api/x", "url": "https://go.dev"}`, http.StatusBadRequest},
+		{"domain path", http.MethodPost, "/api/links", "secret", `{"domain": "Go.example.com", "path": "/taken", "url": "https://go.dev"}`, http.StatusCreated},
+		{"invalid domain", http.MethodPost, "/api/links", "secret", `{"domain": "go.example.com/x", "url": "https://go.dev"}`, http.StatusBadRequest},
+		{"get domain", http.MethodGet, "/api/links/taken?domain=go.example.com", "secret", "", http.StatusOK},
+		{"move domain", http.MethodPut, "/api/links/taken?domain=go.example.com", "secret", `{"domain": "other.example.com", "url": "https://go.dev"}`, http.StatusBadRequest},
+		{"delete domain", http.MethodDelete, "/api/links/taken?domain=go.example.com", "secret", "", http.StatusNoContent},
+		{"get", http.MethodGet, "/api/links/taken", "secret", "", http.StatusOK},
+		{"get missing", http.MethodGet, "/api/links/missing", "secret", "", http.StatusNotFound},
+		{"update", http.MethodPut, "/api/links/taken", "secret", `{"url": "https://go.dev"}`, http.StatusOK},
+		{"update missing", http.MethodPut, "/api/links/missing", "secret", `{"url": "https://go.dev"}`, http.StatusNotFound},
+		{"delete", http.MethodDelete, "/api/links/taken", "secret", "", http.StatusNoContent},
+		{"delete missing", http.MethodDelete, "/api/links/taken", "secret", "", http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := apiRequest(api, tc.method, tc.path, tc.token, tc.body)
+			if rec.Code != tc.status {
+				t.Errorf("%s %s: want %d, got %d (%s)", tc.method, tc.path, tc.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	if link, err := store.Get("", "/docs"); err != nil || link.URL != "https://go.dev/doc" {
+		t.Errorf("Get(/docs): want the created link, got %v, %v", link, err)
+	}
+}
+
+func TestAPIStats(t *testing.T) {
+	store := NewMemoryStore()
+	api := NewAPI(store, "secret")
+	now := time.Now().UTC()
+	store.AddClicks([]Click{
+		{Path: "/docs", Time: now, Visitor: "1"},
+		{Domain: "go.example.com", Path: "/docs", Time: now, Visitor: "1"},
+		{Domain: "go.example.com", Path: "/docs", Time: now, Visitor: "2"},
+	})
+
+	tests := []struct {
+		path   string
+		clicks []int
+	}{
+		{"/api/stats", []int{2, 1}},
+		{"/api/stats?domain=Go.example.com", []int{2}},
+		{"/api/stats?domain=other.example.com", nil},
+	}
+	for _, tc := range tests {
+		rec := apiRequest(api, http.MethodGet, tc.path, "secret", "")
+		var reports []ClickReport
+		if err := json.NewDecoder(rec.Body).Decode(&reports); err != nil {
+			t.Fatalf("GET %s: Decode received an error: %s", tc.path, err.Error())
+		}
+		var clicks []int
+		for _, r := range reports {
+			clicks = append(clicks, r.Clicks)
+		}
+		if fmt.Sprint(clicks) != fmt.Sprint(tc.clicks) {
+			t.Errorf("GET %s: want clicks %v, got %v", tc.path, tc.clicks, clicks)
+		}
+	}
+
+	links := []struct {
+		path   string
+		clicks int
+	}{
+		{"/api/stats/docs", 1},
+		{"/api/stats/docs?domain=go.example.com", 2},
+		{"/api/stats/docs?domain=other.example.com", 0},
+	}
+	for _, tc := range links {
+		rec := apiRequest(api, http.MethodGet, tc.path, "secret", "")
+		var report ClickReport
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatalf("GET %s: Decode received an error: %s", tc.path, err.Error())
+		}
+		if report.Path != "/docs" || report.Clicks != tc.clicks {
+			t.Errorf("GET %s: want %d click(s) of /docs, got %+v", tc.path, tc.clicks, report)
+		}
+	}
+}
+
+func TestAPIGeneratedPath(t *testing.T) {
+	store := NewMemoryStore()
+	api := NewAPI(store, "secret")
+
+	rec := apiRequest(api, http.MethodPost, "/api/links", "secret", `{"url": "https://go.dev"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST: want %d, got %d (%s)", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	var link LinkData
+	if err := json.NewDecoder(rec.Body).Decode(&link); err != nil {
+		t.Fatalf("Decode received an error: %s", err.Error())
+	}
+	if len(link.Path) != slugLength+1 || strings.Trim(link.Path[1:], base62) != "" {
+		t.Errorf("generated path: want / and %d base62 characters, got %q", slugLength, link.Path)
+	}
+	if _, err := store.Get(link.Domain, link.Path); err != nil {
+		t.Errorf("Get(%s) received an error: %s", link.Path, err.Error())
+	}
+}
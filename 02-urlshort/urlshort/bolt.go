@@ -0,0 +1,245 @@
+package urlshort
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	linksBucket = []byte("links")
+	// clicksBucket keys the clicks by time then sequence, so they are sorted
+	clicksBucket = []byte("clicks")
+	// hitsBucket holds the redirect count of the links, for their click limits
+	hitsBucket = []byte("hits")
+	// clickCountsBucket holds the click count of the links, so they are
+	// counted without reading every click
+	clickCountsBucket = []byte("click_counts")
+)
+
+// BoltStore is a Store keeping the links in a BoltDB file, keyed by domain
+// and path
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens or creates the BoltDB file at path
+func OpenBoltStore(path string) (*BoltStore, error) {
+	// the file is locked while open, don't wait forever for another process
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	// make sure the buckets exist once instead of checking on every call
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{linksBucket, clicksBucket, hitsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		// the files made before the counts get them from their clicks
+		if tx.Bucket(clickCountsBucket) != nil {
+			return nil
+		}
+		counts, err := tx.CreateBucket(clickCountsBucket)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(clicksBucket).ForEach(func(k, b []byte) error {
+			var c Click
+			if err := json.Unmarshal(b, &c); err != nil {
+				return err
+			}
+			return addClickCount(counts, c)
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Get implements Store
+func (s *BoltStore) Get(domain, path string) (LinkData, error) {
+	var link LinkData
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(linksBucket).Get([]byte(linkKey(domain, path)))
+		if b == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(b, &link)
+	})
+	return link, err
+}
+
+// Put implements Store
+func (s *BoltStore) Put(link LinkData) error {
+	b, err := json.Marshal(link)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(linksBucket).Put([]byte(link.key()), b)
+	})
+}
+
+// Delete implements Store
+func (s *BoltStore) Delete(domain, path string) error {
+	key := []byte(linkKey(domain, path))
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(linksBucket)
+		if bucket.Get(key) == nil {
+			return ErrNotFound
+		}
+		if err := tx.Bucket(hitsBucket).Delete(key); err != nil {
+			return err
+		}
+		return bucket.Delete(key)
+	})
+}
+
+// List implements Store
+func (s *BoltStore) List() ([]LinkData, error) {
+	var links []LinkData
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(linksBucket).ForEach(func(k, b []byte) error {
+			var link LinkData
+			if err := json.Unmarshal(b, &link); err != nil {
+				return err
+			}
+			links = append(links, link)
+			return nil
+		})
+	})
+	sortLinks(links)
+	return links, err
+}
+
+// Close implements Store
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// Ping implements Pinger, it fails once the file is closed
+func (s *BoltStore) Ping(ctx context.Context) error {
+	return s.db.View(func(tx *bolt.Tx) error { return nil })
+}
+
+// Patterns implements PatternLister
+func (s *BoltStore) Patterns() ([]LinkData, error) {
+	var links []LinkData
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(linksBucket).ForEach(func(k, b []byte) error {
+			if !IsPattern(string(k)) {
+				return nil
+			}
+			var link LinkData
+			if err := json.Unmarshal(b, &link); err != nil {
+				return err
+			}
+			links = append(links, link)
+			return nil
+		})
+	})
+	return links, err
+}
+
+// Hit implements HitCounter
+func (s *BoltStore) Hit(domain, path string) (int, error) {
+	var hits uint64
+	key := []byte(linkKey(domain, path))
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(hitsBucket)
+		if b := bucket.Get(key); b != nil {
+			hits = binary.BigEndian.Uint64(b)
+		}
+		hits++
+		return bucket.Put(key, binary.BigEndian.AppendUint64(nil, hits))
+	})
+	return int(hits), err
+}
+
+// AddClicks implements ClickLog
+func (s *BoltStore) AddClicks(clicks []Click) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(clicksBucket)
+		counts := tx.Bucket(clickCountsBucket)
+		for _, c := range clicks {
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			b, err := json.Marshal(c)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(clickKey(c.Time, seq), b); err != nil {
+				return err
+			}
+			if err := addClickCount(counts, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func addClickCount(counts *bolt.Bucket, c Click) error {
+	key := []byte(linkKey(c.Domain, c.Path))
+	var n uint64
+	if b := counts.Get(key); b != nil {
+		n = binary.BigEndian.Uint64(b)
+	}
+	return counts.Put(key, binary.BigEndian.AppendUint64(nil, n+1))
+}
+
+// CountClicks implements ClickLog
+func (s *BoltStore) CountClicks(domain, path string) (int, error) {
+	var n uint64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(clickCountsBucket).Get([]byte(linkKey(domain, path))); b != nil {
+			n = binary.BigEndian.Uint64(b)
+		}
+		return nil
+	})
+	return int(n), err
+}
+
+// Clicks implements ClickLog
+func (s *BoltStore) Clicks(domain, path string, since time.Time) ([]Click, error) {
+	var clicks []Click
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(clicksBucket).Cursor()
+		for k, b := c.Seek(clickKey(since, 0)); k != nil; k, b = c.Next() {
+			var click Click
+			if err := json.Unmarshal(b, &click); err != nil {
+				return err
+			}
+			if click.matches(domain, path) {
+				clicks = append(clicks, click)
+			}
+		}
+		return nil
+	})
+	return clicks, err
+}
+
+func clickKey(t time.Time, seq uint64) []byte {
+	// UnixNano overflows before 1970, like for the zero time
+	var nanos uint64
+	if t.After(time.Unix(0, 0)) {
+		nanos = uint64(t.UnixNano())
+	}
+	var key bytes.Buffer
+	binary.Write(&key, binary.BigEndian, nanos)
+	binary.Write(&key, binary.BigEndian, seq)
+	return key.Bytes()
+}
@@ -0,0 +1,313 @@
+package urlshort
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// recorderBuffer is how many clicks wait to be saved before new ones are
+	// dropped, so a slow store never slows the redirects down
+	recorderBuffer = 1024
+	batchSize      = 100
+	flushInterval  = time.Second
+)
+
+// Click is one redirect followed by a visitor
+type Click struct {
+	// Domain is the domain of the link followed, empty for the links of
+	// every domain, so the links of a path on several domains are told apart
+	Domain    string    `json:"domain,omitempty"`
+	Path      string    `json:"path"`
+	Time      time.Time `json:"time"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	// Visitor is the hashed IP of the client, see HashVisitor
+	Visitor string `json:"visitor"`
+}
+
+// ClickLog stores the clicks of the links, the stores returned by OpenStore
+// implement it
+type ClickLog interface {
+	// AddClicks saves a batch of clicks
+	AddClicks(clicks []Click) error
+	// Clicks returns the clicks on the link of domain and path since the
+	// given time, oldest first. When path is empty it returns the clicks on
+	// every link of domain, or on every link when domain is empty too
+	Clicks(domain, path string, since time.Time) ([]Click, error)
+	// CountClicks returns how many clicks the link of domain and path has
+	// had, without loading them
+	CountClicks(domain, path string) (int, error)
+}
+
+// matches reports whether c is one of the clicks of ClickLog.Clicks with
+// domain and path
+func (c Click) matches(domain, path string) bool {
+	if path == "" {
+		return domain == "" || c.Domain == domain
+	}
+	return c.Domain == domain && c.Path == path
+}
+
+// HashVisitor identifies a client without storing its IP, the salt keeps the
+// hashes from being reversed by hashing every IP
+func HashVisitor(ip, salt string) string {
+	h := sha256.Sum256([]byte(salt + ip))
+	return hex.EncodeToString(h[:8])
+}
+
+// Recorder saves clicks to a ClickLog in the background, in batches
+type Recorder struct {
+	log    ClickLog
+	clicks chan Click
+	done   chan struct{}
+
+	mu     sync.RWMutex // guards closed, so Record never sends on a closed channel
+	closed bool
+}
+
+// NewRecorder returns a Recorder saving to log until Close is called
+func NewRecorder(log ClickLog) *Recorder {
+	r := &Recorder{
+		log:    log,
+		clicks: make(chan Click, recorderBuffer),
+		done:   make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Record queues c to be saved without waiting, it reports false when the
+// click was dropped because the queue is full or the Recorder closed
+func (r *Recorder) Record(c Click) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return false
+	}
+	select {
+	case r.clicks <- c:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close saves the queued clicks and stops the Recorder
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.clicks)
+	}
+	r.mu.Unlock()
+	<-r.done
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Click, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.log.AddClicks(batch); err != nil {
+			metrics.storeErrors.inc("add_clicks")
+			slog.Error("failed to save clicks", "clicks", len(batch), "err", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case c, ok := <-r.clicks:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, c)
+			if len(batch) == batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// ClickHandler will return an http.HandlerFunc recording a Click for every
+// redirect made by next, identifying the visitors with HashVisitor and salt.
+// The clicks are on the domain of the link StoreHandler redirected with
+func ClickHandler(rec *Recorder, salt string, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		// the preview pages count the clicks of the log
+		domain := new(string)
+		ctx := context.WithValue(withClickLog(r.Context(), rec.log), clickDomainKey{}, domain)
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		switch sw.status {
+		case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+			http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+			ok := rec.Record(Click{
+				Domain:    *domain,
+				Path:      r.URL.Path,
+				Time:      now.UTC(),
+				Referrer:  r.Referer(),
+				UserAgent: r.UserAgent(),
+				Visitor:   HashVisitor(clientIP(r), salt),
+			})
+			if !ok {
+				metrics.droppedClicks.inc("")
+			}
+		}
+	}
+}
+
+// clickDomainKey is the context key of the domain of the link a request is
+// redirected with, set by StoreHandler for ClickHandler
+type clickDomainKey struct{}
+
+func setClickDomain(ctx context.Context, domain string) {
+	if d, ok := ctx.Value(clickDomainKey{}).(*string); ok {
+		*d = domain
+	}
+}
+
+// statusWriter remembers the status code written to a ResponseWriter
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// clientIP returns the IP of the client connected to the server, without
+// trusting headers a client could set itself
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ClickReport sums up the clicks on a link
+type ClickReport struct {
+	Domain    string          `json:"domain,omitempty"`
+	Path      string          `json:"path"`
+	Clicks    int             `json:"clicks"`
+	Visitors  int             `json:"visitors"`
+	Days      []DayClicks     `json:"days"`
+	Referrers []ReferrerCount `json:"referrers"`
+}
+
+// DayClicks are the clicks on a link during one UTC day
+type DayClicks struct {
+	Day      string `json:"day"` // 2006-01-02
+	Clicks   int    `json:"clicks"`
+	Visitors int    `json:"visitors"`
+}
+
+// ReferrerCount is how many clicks came from a referrer, empty for the
+// clicks without one
+type ReferrerCount struct {
+	Referrer string `json:"referrer"`
+	Clicks   int    `json:"clicks"`
+}
+
+// Summarize returns a ClickReport per link of clicks, the most clicked link
+// first, with the top referrers of each link
+func Summarize(clicks []Click, topReferrers int) []ClickReport {
+	type dayKey struct{ link, day string }
+	var (
+		reports   = map[string]*ClickReport{}
+		visitors  = map[string]map[string]bool{}
+		days      = map[dayKey]*DayClicks{}
+		dayVisits = map[dayKey]map[string]bool{}
+		referrers = map[string]map[string]int{}
+	)
+
+	for _, c := range clicks {
+		link := linkKey(c.Domain, c.Path)
+		report, ok := reports[link]
+		if !ok {
+			report = &ClickReport{Domain: c.Domain, Path: c.Path}
+			reports[link] = report
+			visitors[link] = map[string]bool{}
+			referrers[link] = map[string]int{}
+		}
+		report.Clicks++
+		visitors[link][c.Visitor] = true
+		referrers[link][c.Referrer]++
+
+		key := dayKey{link, c.Time.UTC().Format(time.DateOnly)}
+		day, ok := days[key]
+		if !ok {
+			day = &DayClicks{Day: key.day}
+			days[key] = day
+			dayVisits[key] = map[string]bool{}
+		}
+		day.Clicks++
+		dayVisits[key][c.Visitor] = true
+	}
+
+	for key, day := range days {
+		day.Visitors = len(dayVisits[key])
+		reports[key.link].Days = append(reports[key.link].Days, *day)
+	}
+
+	result := make([]ClickReport, 0, len(reports))
+	for link, report := range reports {
+		report.Visitors = len(visitors[link])
+		sort.Slice(report.Days, func(i, j int) bool {
+			return report.Days[i].Day < report.Days[j].Day
+		})
+
+		for referrer, n := range referrers[link] {
+			report.Referrers = append(report.Referrers, ReferrerCount{Referrer: referrer, Clicks: n})
+		}
+		sort.Slice(report.Referrers, func(i, j int) bool {
+			a, b := report.Referrers[i], report.Referrers[j]
+			return a.Clicks > b.Clicks || a.Clicks == b.Clicks && a.Referrer < b.Referrer
+		})
+		if topReferrers > 0 && len(report.Referrers) > topReferrers {
+			report.Referrers = report.Referrers[:topReferrers]
+		}
+
+		result = append(result, *report)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Clicks != b.Clicks {
+			return a.Clicks > b.Clicks
+		}
+		return a.Domain < b.Domain || a.Domain == b.Domain && a.Path < b.Path
+	})
+	return result
+}
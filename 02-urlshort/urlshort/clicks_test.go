@@ -0,0 +1,161 @@
+package urlshort
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestClickLogs(t *testing.T) {
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clicks := []Click{
+		{Path: "/a", Time: day.Add(-48 * time.Hour), Visitor: "1"},
+		{Path: "/a", Time: day, Referrer: "https://example.com", UserAgent: "curl", Visitor: "1"},
+		{Path: "/b", Time: day.Add(time.Hour), Visitor: "2"},
+		{Domain: "go.example.com", Path: "/a", Time: day.Add(2 * time.Hour), Visitor: "3"},
+	}
+
+	for name, store := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			log := store.(ClickLog)
+			if err := log.AddClicks(clicks); err != nil {
+				t.Fatalf("AddClicks received an error: %s", err.Error())
+			}
+
+			got, err := log.Clicks("", "", day.Add(-time.Hour))
+			if err != nil {
+				t.Fatalf("Clicks received an error: %s", err.Error())
+			}
+			if len(got) != 3 || got[0].Path != "/a" || got[1].Path != "/b" || got[2].Domain != "go.example.com" {
+				t.Errorf("Clicks(all): want /a, /b and go.example.com/a, got %v", got)
+			}
+			if len(got) > 0 && (!got[0].Time.Equal(day) || got[0].Referrer != "https://example.com" || got[0].UserAgent != "curl") {
+				t.Errorf("Clicks(all): want %v, got %v", clicks[1], got[0])
+			}
+
+			got, err = log.Clicks("", "/a", time.Time{})
+			if err != nil || len(got) != 2 {
+				t.Errorf("Clicks(/a): want 2 clicks, got %v, %v", got, err)
+			}
+			got, err = log.Clicks("go.example.com", "/a", time.Time{})
+			if err != nil || len(got) != 1 || got[0].Domain != "go.example.com" {
+				t.Errorf("Clicks(go.example.com/a): want 1 click, got %v, %v", got, err)
+			}
+			got, err = log.Clicks("go.example.com", "", time.Time{})
+			if err != nil || len(got) != 1 {
+				t.Errorf("Clicks(go.example.com): want 1 click, got %v, %v", got, err)
+			}
+
+			for _, tc := range []struct {
+				domain, path string
+				want         int
+			}{{"", "/a", 2}, {"", "/b", 1}, {"go.example.com", "/a", 1}, {"", "/c", 0}} {
+				if n, err := log.CountClicks(tc.domain, tc.path); err != nil || n != tc.want {
+					t.Errorf("CountClicks(%s%s): want %d, got %d, %v", tc.domain, tc.path, tc.want, n, err)
+				}
+			}
+		})
+	}
+}
+
+func TestBoltClickCounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.bolt")
+	store, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("OpenBoltStore received an error: %s", err.Error())
+	}
+	store.AddClicks([]Click{{Path: "/a", Time: time.Now()}, {Path: "/a", Time: time.Now()}})
+	// a file made before the counts has none
+	store.db.Update(func(tx *bolt.Tx) error { return tx.DeleteBucket(clickCountsBucket) })
+	store.Close()
+
+	store, err = OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("OpenBoltStore received an error: %s", err.Error())
+	}
+	defer store.Close()
+	if n, err := store.CountClicks("", "/a"); err != nil || n != 2 {
+		t.Errorf("CountClicks(/a) after the upgrade: want 2, got %d, %v", n, err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clicks := []Click{
+		{Path: "/a", Time: day, Referrer: "x", Visitor: "1"},
+		{Path: "/a", Time: day, Referrer: "x", Visitor: "1"},
+		{Path: "/a", Time: day.Add(24 * time.Hour), Visitor: "2"},
+		{Path: "/a", Time: day.Add(24 * time.Hour), Referrer: "y", Visitor: "1"},
+		{Path: "/b", Time: day, Visitor: "3"},
+		{Domain: "go.example.com", Path: "/b", Time: day, Visitor: "3"},
+	}
+
+	want := []ClickReport{
+		{
+			Path:     "/a",
+			Clicks:   4,
+			Visitors: 2,
+			Days: []DayClicks{
+				{Day: "2024-05-01", Clicks: 2, Visitors: 1},
+				{Day: "2024-05-02", Clicks: 2, Visitors: 2},
+			},
+			Referrers: []ReferrerCount{{Referrer: "x", Clicks: 2}, {Referrer: "", Clicks: 1}},
+		},
+		{
+			Path:      "/b",
+			Clicks:    1,
+			Visitors:  1,
+			Days:      []DayClicks{{Day: "2024-05-01", Clicks: 1, Visitors: 1}},
+			Referrers: []ReferrerCount{{Referrer: "", Clicks: 1}},
+		},
+		{
+			Domain:    "go.example.com",
+			Path:      "/b",
+			Clicks:    1,
+			Visitors:  1,
+			Days:      []DayClicks{{Day: "2024-05-01", Clicks: 1, Visitors: 1}},
+			Referrers: []ReferrerCount{{Referrer: "", Clicks: 1}},
+		},
+	}
+	if got := Summarize(clicks, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("Summarize:\nwant %+v\ngot  %+v", want, got)
+	}
+}
+
+func TestClickHandler(t *testing.T) {
+	store := NewMemoryStore(
+		LinkData{Path: "/go", URL: "https://go.dev"},
+		LinkData{Domain: "go.example.com", Path: "/go", URL: "https://go.dev/doc"},
+	)
+	recorder := NewRecorder(store)
+	handler := ClickHandler(recorder, "salt", StoreHandler(store, http.NotFoundHandler()))
+
+	for _, target := range []string{"/go", "/missing", "/go", "http://go.example.com/go"} {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.Header.Set("Referer", "https://example.com")
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	// Close saves the queued clicks
+	recorder.Close()
+
+	clicks, _ := store.Clicks("", "", time.Time{})
+	if len(clicks) != 3 {
+		t.Fatalf("clicks: want 3 redirects recorded, got %d", len(clicks))
+	}
+	c := clicks[0]
+	if c.Domain != "" || c.Path != "/go" || c.Referrer != "https://example.com" || c.Visitor != HashVisitor("192.0.2.1", "salt") {
+		t.Errorf("click: want /go from example.com by a hashed visitor, got %+v", c)
+	}
+	// the click is on the domain of the link, not the host of the request
+	if c := clicks[2]; c.Domain != "go.example.com" || c.Path != "/go" {
+		t.Errorf("click: want go.example.com/go, got %+v", c)
+	}
+	if recorder.Record(c) {
+		t.Errorf("Record after Close: want the click dropped")
+	}
+}
@@ -0,0 +1,75 @@
+package urlshort
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Domain returns the domain of a request host, lowercased and without its
+// port, the form of the Domain of the links
+func Domain(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// validateDomain checks that domain is a bare host name
+func validateDomain(domain string) error {
+	if domain != Domain(domain) || strings.ContainsAny(domain, "/:@ ") {
+		return fmt.Errorf("invalid domain %q, expected a lowercase host name like go.example.com", domain)
+	}
+	return nil
+}
+
+// linkKey identifies a link in the stores, domains have no slash and paths
+// start with one so the keys never collide
+func linkKey(domain, path string) string {
+	return domain + path
+}
+
+func (l LinkData) key() string {
+	return linkKey(l.Domain, l.Path)
+}
+
+// sortLinks sorts links by domain then path
+func sortLinks(links []LinkData) {
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].Domain != links[j].Domain {
+			return links[i].Domain < links[j].Domain
+		}
+		return links[i].Path < links[j].Path
+	})
+}
+
+// DomainHandler will return an http.HandlerFunc passing the requests to the
+// handler of their domain in handlers, like the fallback or default page of a
+// domain, the requests of the other domains go to fallback
+func DomainHandler(handlers map[string]http.Handler, fallback http.Handler) http.HandlerFunc {
+	byDomain := make(map[string]http.Handler, len(handlers))
+	for domain, h := range handlers {
+		byDomain[Domain(domain)] = h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h, ok := byDomain[Domain(r.Host)]; ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		fallback.ServeHTTP(w, r)
+	}
+}
+
+// PageHandler will return an http.HandlerFunc serving the HTML page as the
+// default page of a domain, on / and as the not found page of the other paths
+func PageHandler(page []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write(page)
+	}
+}
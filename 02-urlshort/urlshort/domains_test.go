@@ -0,0 +1,100 @@
+package urlshort
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStoreHandlerDomains(t *testing.T) {
+	store := NewMemoryStore(
+		LinkData{Path: "/docs", URL: "https://example.com/docs"},
+		LinkData{Path: "/gh/*", URL: "https://github.com/*"},
+		LinkData{Domain: "go.example.com", Path: "/docs", URL: "https://go.dev/doc"},
+		LinkData{Domain: "go.example.com", Path: "/pkg/*", URL: "https://pkg.go.dev/*"},
+	)
+	handler := StoreHandler(store, http.NotFoundHandler())
+
+	for _, tc := range []struct {
+		host, path string
+		status     int
+		location   string
+	}{
+		{"go.example.com", "/docs", http.StatusMovedPermanently, "https://go.dev/doc"},
+		{"GO.example.com:8080", "/docs", http.StatusMovedPermanently, "https://go.dev/doc"},
+		{"short.example.com", "/docs", http.StatusMovedPermanently, "https://example.com/docs"},
+		{"go.example.com", "/pkg/net/http", http.StatusMovedPermanently, "https://pkg.go.dev/net/http"},
+		{"short.example.com", "/pkg/net/http", http.StatusNotFound, ""},
+		// the links without a domain answer on every domain
+		{"go.example.com", "/gh/golang/go", http.StatusMovedPermanently, "https://github.com/golang/go"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Host = tc.host
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.status || rec.Header().Get("Location") != tc.location {
+			t.Errorf("%s%s: want %d %s, got %d %s", tc.host, tc.path, tc.status, tc.location, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+}
+
+func TestDomainHandler(t *testing.T) {
+	handler := DomainHandler(map[string]http.Handler{
+		"Go.Example.com": PageHandler([]byte("go links")),
+	}, http.NotFoundHandler())
+
+	for _, tc := range []struct {
+		host, path string
+		status     int
+		body       string
+	}{
+		{"go.example.com", "/", http.StatusOK, "go links"},
+		{"go.example.com:8080", "/missing", http.StatusNotFound, "go links"},
+		{"short.example.com", "/", http.StatusNotFound, "404 page not found"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Host = tc.host
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.status || strings.TrimSpace(rec.Body.String()) != tc.body {
+			t.Errorf("%s%s: want %d %q, got %d %q", tc.host, tc.path, tc.status, tc.body, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestDomain(t *testing.T) {
+	for host, want := range map[string]string{
+		"go.example.com":      "go.example.com",
+		"Go.Example.COM:8080": "go.example.com",
+		"go.example.com.":     "go.example.com",
+		"[::1]:8080":          "::1",
+		"":                    "",
+	} {
+		if got := Domain(host); got != want {
+			t.Errorf("Domain(%q): want %q, got %q", host, want, got)
+		}
+	}
+}
+
+func TestLoadLinksDomains(t *testing.T) {
+	data := `path,url,domain
+/docs,https://example.com/docs,
+/docs,https://go.dev/doc,Go.Example.com
+/docs,https://go.dev,go.example.com
+/pkg,http://go.example.com/pkg,go.example.com
+/x,https://go.dev,go.example.com/x
+`
+	_, err := LoadLinks("urls.csv", []byte(data))
+	want := "urls.csv:4: duplicate path go.example.com/docs, first defined on line 3\n" +
+		"urls.csv:6: link /x: invalid domain \"go.example.com/x\", expected a lowercase host name like go.example.com\n" +
+		"urls.csv:5: redirect loop: go.example.com/pkg -> go.example.com/pkg"
+	if err == nil || err.Error() != want {
+		t.Errorf("LoadLinks:\nwant %s\ngot  %v", want, err)
+	}
+
+	links, err := LoadLinks("urls.csv", []byte("path,url,domain\n/docs,https://go.dev/doc,Go.Example.com\n"))
+	if err != nil || len(links) != 1 || links[0].Domain != "go.example.com" {
+		t.Errorf("LoadLinks: want a link of go.example.com, got %+v, %v", links, err)
+	}
+}
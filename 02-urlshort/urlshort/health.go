@@ -0,0 +1,75 @@
+package urlshort
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// pingTimeout bounds the store check of a health request
+const pingTimeout = 2 * time.Second
+
+// Pinger checks that a store can be reached, the stores returned by
+// OpenStore implement it
+type Pinger interface {
+	// Ping returns an error when the store can't serve the links
+	Ping(ctx context.Context) error
+}
+
+// Health serves the health checks of the shortener:
+//
+//	/healthz   200 while the store answers
+//	/readyz    200 while the store answers and the server isn't shutting down
+//
+// The stores which aren't a Pinger are always healthy
+type Health struct {
+	pinger   Pinger
+	draining atomic.Bool
+}
+
+// NewHealth returns the Health of the shortener using store
+func NewHealth(store Store) *Health {
+	pinger, _ := store.(Pinger)
+	return &Health{pinger: pinger}
+}
+
+// Drain fails the readiness checks from now on, so the load balancers stop
+// sending requests before the server shuts down
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Live handles /healthz
+func (h *Health) Live(w http.ResponseWriter, r *http.Request) {
+	if err := h.ping(r.Context()); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "error": "store unreachable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Ready handles /readyz
+func (h *Health) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "shutting down"})
+		return
+	}
+	h.Live(w, r)
+}
+
+func (h *Health) ping(ctx context.Context) error {
+	if h.pinger == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	if err := h.pinger.Ping(ctx); err != nil {
+		metrics.storeErrors.inc("ping")
+		slog.Warn("store ping failed", "err", err)
+		return err
+	}
+	return nil
+}
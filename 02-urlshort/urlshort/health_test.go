@@ -0,0 +1,36 @@
+package urlshort
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	for name, store := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			health := NewHealth(store)
+			check := func(handler http.HandlerFunc, path string, want int) {
+				t.Helper()
+				rec := httptest.NewRecorder()
+				handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+				if rec.Code != want {
+					t.Errorf("%s: want %d, got %d (%s)", path, want, rec.Code, rec.Body.String())
+				}
+			}
+
+			check(health.Live, "/healthz", http.StatusOK)
+			check(health.Ready, "/readyz", http.StatusOK)
+
+			health.Drain()
+			check(health.Live, "/healthz", http.StatusOK)
+			check(health.Ready, "/readyz", http.StatusServiceUnavailable)
+
+			if name == "memory" {
+				return
+			}
+			store.Close()
+			check(health.Live, "/healthz", http.StatusServiceUnavailable)
+		})
+	}
+}
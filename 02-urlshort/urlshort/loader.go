@@ -0,0 +1,558 @@
+package urlshort
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// The formats of the links files
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatTOML = "toml"
+	FormatCSV  = "csv"
+)
+
+// linkFields are the names of the LinkData fields in every format
+var linkFields = []string{"path", "url", "expires_at", "max_clicks", "status", "enabled", "domain", "created_at"}
+
+// LinkError is a problem with a links file
+type LinkError struct {
+	File    string // empty when the links were not read from a file
+	Line    int    // 0 when the problem is about the whole file
+	Message string
+}
+
+func (e LinkError) Error() string {
+	switch {
+	case e.File != "" && e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+	case e.File != "":
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	case e.Line > 0:
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return e.Message
+}
+
+// LinkErrors are every problem found in a links file
+type LinkErrors []LinkError
+
+func (e LinkErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// LoadOption configures LoadLinks
+type LoadOption func(*loader)
+
+// OptionFormat sets the format of the links instead of detecting it
+func OptionFormat(format string) LoadOption {
+	return func(l *loader) {
+		l.format = format
+	}
+}
+
+// OptionHosts sets the hosts the shortener answers on, like
+// "localhost:8080", so the links redirecting back into the shortener are
+// checked for redirect loops. The domains of the links are hosts of the
+// shortener too
+func OptionHosts(hosts ...string) LoadOption {
+	return func(l *loader) {
+		for _, host := range hosts {
+			if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+				l.hosts[host] = true
+			}
+		}
+	}
+}
+
+// LoadFile reads the links file at path with LoadLinks
+func LoadFile(path string, opts ...LoadOption) ([]LinkData, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadLinks(path, data, opts...)
+}
+
+// LoadLinks parses and validates the links of a YAML, JSON, TOML or CSV file,
+// name is the file used in the errors and to detect the format, see
+// DetectFormat. All the problems are returned at once as LinkErrors: empty
+// paths, relative URLs, invalid rules or patterns, duplicate paths and
+// redirect loops
+//
+// TOML files list the links as [[links]] tables, CSV files start with a
+// header naming the columns, like path,url,status
+func LoadLinks(name string, data []byte, opts ...LoadOption) ([]LinkData, error) {
+	l := &loader{file: name, hosts: map[string]bool{}, first: map[string]int{}}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.format == "" {
+		l.format = DetectFormat(name, data)
+	}
+
+	switch l.format {
+	case FormatYAML:
+		l.yaml(data)
+	case FormatJSON:
+		l.json(data)
+	case FormatTOML:
+		l.toml(data)
+	case FormatCSV:
+		l.csv(data)
+	default:
+		l.addf(0, "unknown links format %q", l.format)
+	}
+	l.checkLoops()
+
+	if len(l.errs) > 0 {
+		return nil, l.errs
+	}
+	links := make([]LinkData, len(l.entries))
+	for i, e := range l.entries {
+		links[i] = e.link
+	}
+	return links, nil
+}
+
+// DetectFormat returns the format of a links file from the extension of
+// name, or from the look of data when the extension is not known
+func DetectFormat(name string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	case ".csv":
+		return FormatCSV
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	firstLine, _, _ := bytes.Cut(trimmed, []byte("\n"))
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[[")):
+		return FormatTOML
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		return FormatJSON
+	case bytes.Contains(firstLine, []byte(",")) && bytes.Contains(bytes.ToLower(firstLine), []byte("path")):
+		return FormatCSV
+	}
+	return FormatYAML
+}
+
+// entry is a parsed link and the line it starts on
+type entry struct {
+	link LinkData
+	line int
+}
+
+type loader struct {
+	file    string
+	format  string
+	hosts   map[string]bool
+	entries []entry        // the valid links
+	first   map[string]int // line of every domain and path seen
+	errs    LinkErrors
+}
+
+func (l *loader) addf(line int, format string, a ...any) {
+	l.errs = append(l.errs, LinkError{File: l.file, Line: line, Message: fmt.Sprintf(format, a...)})
+}
+
+// fields reports the unknown fields of a link
+func (l *loader) fields(line int, keys []string) {
+	for _, key := range keys {
+		if !knownField(key) {
+			l.addf(line, "unknown field %q", key)
+		}
+	}
+}
+
+// add validates a parsed link and keeps it when valid
+func (l *loader) add(line int, link LinkData) {
+	valid := true
+	invalid := func(format string, a ...any) {
+		l.addf(line, format, a...)
+		valid = false
+	}
+	link.Domain = strings.ToLower(strings.TrimSpace(link.Domain))
+
+	switch {
+	case link.Path == "":
+		invalid("missing path")
+	case !strings.HasPrefix(link.Path, "/"):
+		invalid("path %q must start with /", link.Path)
+	}
+	if err := ValidateURL(link.URL); err != nil {
+		invalid("%v", err)
+	}
+	if link.Path != "" {
+		if err := link.Validate(); err != nil {
+			invalid("%v", err)
+		}
+		if first, ok := l.first[link.key()]; ok {
+			invalid("duplicate path %s, first defined on line %d", link.key(), first)
+		} else {
+			l.first[link.key()] = line
+		}
+	}
+
+	if valid {
+		l.entries = append(l.entries, entry{link: link, line: line})
+	}
+}
+
+func (l *loader) yaml(data []byte) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		l.yamlError(0, err)
+		return
+	}
+	if len(doc.Content) == 0 {
+		return
+	}
+	list := doc.Content[0]
+	if list.Kind != yaml.SequenceNode {
+		l.addf(list.Line, "expected a list of links")
+		return
+	}
+
+	for _, node := range list.Content {
+		if node.Kind != yaml.MappingNode {
+			l.addf(node.Line, "expected a link with a path and a url")
+			continue
+		}
+		var keys []string
+		for i := 0; i < len(node.Content); i += 2 {
+			keys = append(keys, node.Content[i].Value)
+		}
+		l.fields(node.Line, keys)
+
+		var link LinkData
+		if err := node.Decode(&link); err != nil {
+			l.yamlError(node.Line, err)
+			continue
+		}
+		l.add(node.Line, link)
+	}
+}
+
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): `)
+
+// yamlError reports the errors of yaml with their own line when they have one
+func (l *loader) yamlError(line int, err error) {
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+	for _, message := range messages {
+		at := line
+		if m := yamlLine.FindStringSubmatch(message); m != nil {
+			at, _ = strconv.Atoi(m[1])
+			message = message[len(m[0]):]
+		}
+		l.addf(at, "%s", strings.TrimPrefix(message, "yaml: "))
+	}
+}
+
+func (l *loader) json(data []byte) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	lineAt := func(offset int64) int {
+		if offset > int64(len(data)) {
+			offset = int64(len(data))
+		}
+		return bytes.Count(data[:offset], []byte("\n")) + 1
+	}
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		l.addf(lineAt(dec.InputOffset()), "expected an array of links")
+		return
+	}
+
+	for dec.More() {
+		// InputOffset is right after the previous value, skip to the object
+		offset := dec.InputOffset()
+		for offset < int64(len(data)) && bytes.IndexByte([]byte(" \t\r\n,"), data[offset]) >= 0 {
+			offset++
+		}
+		line := lineAt(offset)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				line = lineAt(syntaxErr.Offset)
+			}
+			l.addf(line, "invalid JSON: %v", err)
+			return
+		}
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(value, &raw); err != nil {
+			l.addf(line, "expected a link with a path and a url")
+			continue
+		}
+		// encoding/json matches the field names regardless of their case
+		keys := make([]string, 0, len(raw))
+		for key := range raw {
+			keys = append(keys, strings.ToLower(key))
+		}
+		sort.Strings(keys)
+		l.fields(line, keys)
+
+		var link LinkData
+		if err := json.Unmarshal(value, &link); err != nil {
+			at := line
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				at = line + bytes.Count(value[:typeErr.Offset], []byte("\n"))
+			}
+			l.addf(at, "%v", err)
+			continue
+		}
+		l.add(line, link)
+	}
+}
+
+var tomlLinksHeader = regexp.MustCompile(`(?m)^[ \t]*\[\[[ \t]*links[ \t]*\]\][ \t]*(?:#.*)?$`)
+
+func (l *loader) toml(data []byte) {
+	// the whole file first, for the syntax errors
+	var doc map[string]any
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			l.addf(parseErr.Position.Line, "%s", parseErr.Message)
+		} else {
+			l.addf(0, "%v", err)
+		}
+		return
+	}
+	for key := range doc {
+		if key != "links" {
+			l.addf(0, "unknown key %q, the links are [[links]] tables", key)
+		}
+	}
+	if _, ok := doc["links"].([]map[string]any); doc["links"] != nil && !ok {
+		l.addf(0, "the links must be [[links]] tables")
+		return
+	}
+
+	// then every [[links]] table on its own, so its line is known
+	headers := tomlLinksHeader.FindAllIndex(data, -1)
+	for i, h := range headers {
+		end := len(data)
+		if i+1 < len(headers) {
+			end = headers[i+1][0]
+		}
+		line := bytes.Count(data[:h[0]], []byte("\n")) + 1
+
+		var link LinkData
+		md, err := toml.Decode(string(data[h[1]:end]), &link)
+		if err != nil {
+			var parseErr toml.ParseError
+			if errors.As(err, &parseErr) {
+				l.addf(line+parseErr.Position.Line-1, "%s", parseErr.Message)
+			} else {
+				l.addf(line, "%v", err)
+			}
+			continue
+		}
+		var keys []string
+		for _, key := range md.Keys() {
+			keys = append(keys, key.String())
+		}
+		l.fields(line, keys)
+		l.add(line, link)
+	}
+}
+
+func (l *loader) csv(data []byte) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var columns map[string]int
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			l.addf(parseErr.Line, "invalid CSV: %v", parseErr.Err)
+			return
+		}
+		if err != nil {
+			l.addf(0, "invalid CSV: %v", err)
+			return
+		}
+		line, _ := r.FieldPos(0)
+
+		if columns == nil {
+			columns = map[string]int{}
+			for i, name := range record {
+				name = strings.ToLower(strings.TrimSpace(name))
+				if !knownField(name) {
+					l.addf(line, "unknown column %q", name)
+				}
+				if _, ok := columns[name]; ok {
+					l.addf(line, "duplicate column %q", name)
+				}
+				columns[name] = i
+			}
+			if _, ok := columns["path"]; !ok {
+				l.addf(line, "missing path column in the header")
+				return
+			}
+			if _, ok := columns["url"]; !ok {
+				l.addf(line, "missing url column in the header")
+				return
+			}
+			continue
+		}
+
+		if len(record) > len(columns) {
+			l.addf(line, "%d extra field(s), expected at most %d", len(record)-len(columns), len(columns))
+		}
+		link, err := csvLink(columns, record)
+		if err != nil {
+			l.addf(line, "%v", err)
+			continue
+		}
+		l.add(line, link)
+	}
+}
+
+// csvLink reads a CSV record, the empty cells leave their field unset
+func csvLink(columns map[string]int, record []string) (LinkData, error) {
+	cell := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	link := LinkData{Path: cell("path"), URL: cell("url"), Domain: cell("domain")}
+	for _, f := range []struct {
+		name string
+		dst  **time.Time
+	}{{"expires_at", &link.ExpiresAt}, {"created_at", &link.CreatedAt}} {
+		if v := cell(f.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return link, fmt.Errorf("invalid %s %q, expected a time like 2006-01-02T15:04:05Z", f.name, v)
+			}
+			*f.dst = &t
+		}
+	}
+	for _, f := range []struct {
+		name string
+		dst  *int
+	}{{"max_clicks", &link.MaxClicks}, {"status", &link.Status}} {
+		if v := cell(f.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return link, fmt.Errorf("invalid %s %q, expected a number", f.name, v)
+			}
+			*f.dst = n
+		}
+	}
+	if v := cell("enabled"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return link, fmt.Errorf("invalid enabled %q, expected true or false", v)
+		}
+		link.Enabled = &enabled
+	}
+	return link, nil
+}
+
+// checkLoops reports the links redirecting back to themselves through the
+// hosts of the shortener
+func (l *loader) checkLoops() {
+	store := NewMemoryStore()
+	for _, e := range l.entries {
+		store.Put(e.link)
+		if e.link.Domain != "" {
+			l.hosts[e.link.Domain] = true
+		}
+	}
+	if len(l.hosts) == 0 {
+		return
+	}
+
+	for _, e := range l.entries {
+		chain := []string{e.link.key()}
+		seen := map[string]bool{e.link.key(): true}
+		target := e.link.URL
+		for {
+			domain, path, ok := l.internal(target)
+			if !ok {
+				break
+			}
+			next, err := lookup(store, store, domain, path)
+			if err != nil {
+				break // the fallback answers, not a loop
+			}
+			chain = append(chain, linkKey(next.Domain, path))
+			if next.key() == e.link.key() {
+				l.addf(e.line, "redirect loop: %s", strings.Join(chain, " -> "))
+				break
+			}
+			if seen[next.key()] {
+				break // a loop this link leads to, reported on its own links
+			}
+			seen[next.key()] = true
+			target = next.URL
+		}
+	}
+}
+
+// internal returns the domain and path of target when it points to the
+// shortener
+func (l *loader) internal(target string) (string, string, bool) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", "", false
+	}
+	domain := Domain(u.Host)
+	if !l.hosts[strings.ToLower(u.Host)] && !l.hosts[domain] {
+		return "", "", false
+	}
+	if u.Path == "" {
+		return domain, "/", true
+	}
+	return domain, u.Path, true
+}
+
+func knownField(field string) bool {
+	for _, f := range linkFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package urlshort
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadLinksFormats(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	disabled := false
+	want := []LinkData{
+		{Path: "/a", URL: "https://a.example.com"},
+		{Path: "/b", URL: "https://b.example.com", ExpiresAt: &expiresAt, MaxClicks: 5, Status: 302, Enabled: &disabled},
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"urls.yaml", `
+- path: /a
+  url: https://a.example.com
+- path: /b
+  url: https://b.example.com
+  expires_at: 2030-01-02T03:04:05Z
+  max_clicks: 5
+  status: 302
+  enabled: false
+`},
+		{"urls.json", `[
+  {"path": "/a", "url": "https://a.example.com"},
+  {"Path": "/b", "URL": "https://b.example.com", "expires_at": "2030-01-02T03:04:05Z",
+   "max_clicks": 5, "status": 302, "enabled": false}
+]`},
+		{"urls.toml", `
+[[links]]
+path = "/a"
+url = "https://a.example.com"
+
+[[links]]
+path = "/b"
+url = "https://b.example.com"
+expires_at = 2030-01-02T03:04:05Z
+max_clicks = 5
+status = 302
+enabled = false
+`},
+		{"urls.csv", `path,url,expires_at,max_clicks,status,enabled
+/a,https://a.example.com,,,,
+/b,https://b.example.com,2030-01-02T03:04:05Z,5,302,false
+`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := LoadLinks(tc.name, []byte(tc.data))
+			if err != nil {
+				t.Fatalf("LoadLinks received an error: %s", err.Error())
+			}
+			if len(got) == 2 && got[1].ExpiresAt != nil && got[1].ExpiresAt.Equal(expiresAt) {
+				got[1].ExpiresAt = &expiresAt
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("LoadLinks: want %+v, got %+v", want, got)
+			}
+
+			// the format is detected without the extension too
+			if format := DetectFormat("links", []byte(tc.data)); format != tc.name[len("urls."):] {
+				t.Errorf("DetectFormat: want %s, got %s", tc.name[len("urls."):], format)
+			}
+		})
+	}
+}
+
+func TestLoadLinksErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []LinkError
+	}{
+		{"urls.yaml", `
+- path: /a
+  url: https://a.example.com
+- path: /a
+  url: /relative
+- url: https://b.example.com
+  color: red
+- path: /c
+  url: [x]
+`, []LinkError{
+			{Line: 4, Message: "invalid url \"/relative\": only http and https urls are allowed"},
+			{Line: 4, Message: "duplicate path /a, first defined on line 2"},
+			{Line: 6, Message: "unknown field \"color\""},
+			{Line: 6, Message: "missing path"},
+			{Line: 9, Message: "cannot unmarshal !!seq into string"},
+		}},
+		{"urls.json", "[\n  {\"path\": \"/a\", \"url\": \"https://go.dev\"},\n  {\"path\": \"/b\",\n   \"url\": 1}\n]", []LinkError{
+			{Line: 4, Message: "json: cannot unmarshal number into Go struct field LinkData.url of type string"},
+		}},
+		{"urls.json", "[\n  {\"path\": \"/a\", \"url\": \"https://go.dev\"},\n  {\"path\": \"/b\" \"url\": \"x\"}\n]", []LinkError{
+			{Line: 3, Message: "invalid JSON: invalid character '\"' after object key:value pair"},
+		}},
+		{"urls.toml", "[[links]]\npath = \"/a\"\nurl = \"https://go.dev\"\n\n[[links]]\npath = \"b\"\nurl = \"https://go.dev\"\nstatus = 200\n", []LinkError{
+			{Line: 5, Message: "path \"b\" must start with /"},
+			{Line: 5, Message: "link b: invalid status 200, expected 301, 302, 307 or 308"},
+		}},
+		{"urls.csv", "path,url\n/a,https://go.dev\n/b,https://go.dev,extra\n/c,ftp://go.dev\n", []LinkError{
+			{Line: 3, Message: "1 extra field(s), expected at most 2"},
+			{Line: 4, Message: "invalid url \"ftp://go.dev\": only http and https urls are allowed"},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadLinks("", []byte(tc.data), OptionFormat(DetectFormat(tc.name, nil)))
+			var got LinkErrors
+			if !errors.As(err, &got) {
+				t.Fatalf("LoadLinks: want LinkErrors, got %v", err)
+			}
+			if !reflect.DeepEqual([]LinkError(got), tc.want) {
+				t.Errorf("LoadLinks:\nwant %v\ngot  %v", LinkErrors(tc.want), got)
+			}
+		})
+	}
+}
+
+func TestLoadLinksLoops(t *testing.T) {
+	data := `
+- path: /a
+  url: http://short.example/b
+- path: /b
+  url: https://SHORT.example/a?from=b
+- path: /c
+  url: http://localhost:8080/go/c
+- path: /go/*
+  url: http://localhost:8080/c
+- path: /d
+  url: http://short.example/a
+- path: /e
+  url: http://short.example/missing
+`
+	_, err := LoadLinks("urls.yaml", []byte(data), OptionHosts("short.example", "localhost:8080"))
+	want := "urls.yaml:2: redirect loop: /a -> /b -> /a\n" +
+		"urls.yaml:4: redirect loop: /b -> /a -> /b\n" +
+		"urls.yaml:6: redirect loop: /c -> /go/c -> /c\n" +
+		"urls.yaml:8: redirect loop: /go/* -> /c -> /go/c"
+	if err == nil || err.Error() != want {
+		t.Errorf("LoadLinks:\nwant %s\ngot  %v", want, err)
+	}
+
+	// without the hosts the links are only other URLs
+	if _, err := LoadLinks("urls.yaml", []byte(data)); err != nil {
+		t.Errorf("LoadLinks without hosts received an error: %s", err.Error())
+	}
+}
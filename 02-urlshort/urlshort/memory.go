@@ -0,0 +1,151 @@
+package urlshort
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store keeping the links in a map, safe for concurrent use
+type MemoryStore struct {
+	mu     sync.RWMutex
+	links  map[string]LinkData
+	hits   map[string]int
+	clicks []Click
+}
+
+// NewMemoryStore returns a MemoryStore holding links, a later link replaces
+// an earlier one with the same domain and path
+func NewMemoryStore(links ...LinkData) *MemoryStore {
+	s := &MemoryStore{
+		links: make(map[string]LinkData, len(links)),
+		hits:  map[string]int{},
+	}
+	for _, link := range links {
+		s.links[link.key()] = link
+	}
+	return s
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(domain, path string) (LinkData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	link, ok := s.links[linkKey(domain, path)]
+	if !ok {
+		return LinkData{}, ErrNotFound
+	}
+	return link, nil
+}
+
+// Put implements Store
+func (s *MemoryStore) Put(link LinkData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.links[link.key()] = link
+	return nil
+}
+
+// Delete implements Store
+func (s *MemoryStore) Delete(domain, path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := linkKey(domain, path)
+	if _, ok := s.links[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.links, key)
+	delete(s.hits, key)
+	return nil
+}
+
+// List implements Store
+func (s *MemoryStore) List() ([]LinkData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	links := make([]LinkData, 0, len(s.links))
+	for _, link := range s.links {
+		links = append(links, link)
+	}
+	sortLinks(links)
+	return links, nil
+}
+
+// Close implements Store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// Ping implements Pinger
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Patterns implements PatternLister
+func (s *MemoryStore) Patterns() ([]LinkData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var links []LinkData
+	for _, link := range s.links {
+		if IsPattern(link.Path) {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+// Hit implements HitCounter
+func (s *MemoryStore) Hit(domain, path string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := linkKey(domain, path)
+	s.hits[key]++
+	return s.hits[key], nil
+}
+
+// AddClicks implements ClickLog
+func (s *MemoryStore) AddClicks(clicks []Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clicks = append(s.clicks, clicks...)
+	return nil
+}
+
+// CountClicks implements ClickLog
+func (s *MemoryStore) CountClicks(domain, path string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, c := range s.clicks {
+		if c.Domain == domain && c.Path == path {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Clicks implements ClickLog
+func (s *MemoryStore) Clicks(domain, path string, since time.Time) ([]Click, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var clicks []Click
+	for _, c := range s.clicks {
+		if c.matches(domain, path) && !c.Time.Before(since) {
+			clicks = append(clicks, c)
+		}
+	}
+	sort.SliceStable(clicks, func(i, j int) bool {
+		return clicks[i].Time.Before(clicks[j].Time)
+	})
+	return clicks, nil
+}
@@ -0,0 +1,169 @@
+package urlshort
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds in seconds of the latency histograms
+var latencyBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+// metrics are the metrics of every handler of the package, served by
+// MetricsHandler
+var metrics = struct {
+	redirects     *counter
+	misses        *counter
+	gone          *counter
+	storeErrors   *counter
+	droppedClicks *counter
+	durations     *histogram
+}{
+	redirects:     newCounter("urlshort_redirects_total", "Redirects made, by status code.", "status"),
+	misses:        newCounter("urlshort_misses_total", "Requests without a link, counted by CountMisses.", ""),
+	gone:          newCounter("urlshort_gone_total", "Requests of expired links or links out of clicks.", "reason"),
+	storeErrors:   newCounter("urlshort_store_errors_total", "Failed store operations, by operation.", "op"),
+	droppedClicks: newCounter("urlshort_dropped_clicks_total", "Clicks dropped because the recorder was full or closed.", ""),
+	durations:     newHistogram("urlshort_request_duration_seconds", "Latency of the requests, by handler.", "handler"),
+}
+
+// counter is a Prometheus counter, with one value per value of its label or
+// a single value when it has no label
+type counter struct {
+	name, help, label string
+
+	mu     sync.Mutex
+	values map[string]uint64
+}
+
+func newCounter(name, help, label string) *counter {
+	return &counter{name: name, help: help, label: label, values: map[string]uint64{}}
+}
+
+func (c *counter) inc(value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[value]++
+}
+
+func (c *counter) get(value string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[value]
+}
+
+func (c *counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	if c.label == "" {
+		fmt.Fprintf(w, "%s %d\n", c.name, c.values[""])
+		return
+	}
+	for _, value := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s{%s=%q} %d\n", c.name, c.label, value, c.values[value])
+	}
+}
+
+// histogram is a Prometheus histogram of latencies over latencyBuckets, with
+// one series per value of its label
+type histogram struct {
+	name, help, label string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(name, help, label string) *histogram {
+	return &histogram{name: name, help: help, label: label, series: map[string]*series{}}
+}
+
+func (h *histogram) observe(value string, d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[value]
+	if !ok {
+		s = &series{counts: make([]uint64, len(latencyBuckets))}
+		h.series[value] = s
+	}
+	seconds := d.Seconds()
+	if i := sort.SearchFloat64s(latencyBuckets, seconds); i < len(latencyBuckets) {
+		s.counts[i]++
+	}
+	s.sum += seconds
+	s.count++
+}
+
+func (h *histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, value := range sortedKeys(h.series) {
+		s := h.series[value]
+		var cumulative uint64
+		for i, le := range latencyBuckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket{%s=%q,le=%q} %d\n", h.name, h.label, value, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{%s=%q,le=\"+Inf\"} %d\n", h.name, h.label, value, s.count)
+		fmt.Fprintf(w, "%s_sum{%s=%q} %g\n", h.name, h.label, value, s.sum)
+		fmt.Fprintf(w, "%s_count{%s=%q} %d\n", h.name, h.label, value, s.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// MetricsHandler will return an http.HandlerFunc serving the metrics of the
+// redirects, the misses, the store errors and the latency of the handlers
+// wrapped by InstrumentHandler, in the Prometheus text format
+func MetricsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		metrics.redirects.write(w)
+		metrics.misses.write(w)
+		metrics.gone.write(w)
+		metrics.storeErrors.write(w)
+		metrics.droppedClicks.write(w)
+		metrics.durations.write(w)
+	}
+}
+
+// CountMisses will return an http.HandlerFunc counting the requests reaching
+// next as misses. It wraps the last fallback of the handlers, the fallbacks
+// in between are other links
+func CountMisses(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		metrics.misses.inc("")
+		next.ServeHTTP(w, r)
+	}
+}
+
+// InstrumentHandler will return an http.HandlerFunc measuring the latency
+// of next, reported by MetricsHandler under the handler label name
+func InstrumentHandler(name string, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		metrics.durations.observe(name, time.Since(start))
+	}
+}
@@ -0,0 +1,71 @@
+package urlshort
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreHandlerMetrics(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	store := NewMemoryStore(
+		LinkData{Path: "/a", URL: "https://a.example.com", Status: 302},
+		LinkData{Path: "/old", URL: "https://old.example.com", ExpiresAt: &expired},
+	)
+	handler := StoreHandler(store, CountMisses(http.NotFoundHandler()))
+
+	redirects := metrics.redirects.get("302")
+	misses := metrics.misses.get("")
+	gone := metrics.gone.get("expired")
+	for _, path := range []string{"/a", "/a", "/missing", "/old"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := metrics.redirects.get("302") - redirects; got != 2 {
+		t.Errorf("redirects: want 2, got %d", got)
+	}
+	if got := metrics.misses.get("") - misses; got != 1 {
+		t.Errorf("misses: want 1, got %d", got)
+	}
+	if got := metrics.gone.get("expired") - gone; got != 1 {
+		t.Errorf("gone: want 1, got %d", got)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	h := newHistogram("test_duration_seconds", "Test latency.", "handler")
+	h.observe("api", 3*time.Millisecond)
+	h.observe("api", 2*time.Second)
+
+	var b strings.Builder
+	h.write(&b)
+	for _, want := range []string{
+		"# TYPE test_duration_seconds histogram\n",
+		`test_duration_seconds_bucket{handler="api",le="0.0025"} 0` + "\n",
+		`test_duration_seconds_bucket{handler="api",le="0.005"} 1` + "\n",
+		`test_duration_seconds_bucket{handler="api",le="2.5"} 2` + "\n",
+		`test_duration_seconds_bucket{handler="api",le="+Inf"} 2` + "\n",
+		`test_duration_seconds_count{handler="api"} 2` + "\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("histogram: want %q in\n%s", want, b.String())
+		}
+	}
+
+	handler := InstrumentHandler("test", http.NotFoundHandler())
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		"# TYPE urlshort_redirects_total counter\n",
+		"urlshort_misses_total ",
+		`urlshort_request_duration_seconds_count{handler="test"} 1` + "\n",
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("/metrics: want %q in\n%s", want, rec.Body.String())
+		}
+	}
+}
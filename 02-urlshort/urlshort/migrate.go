@@ -0,0 +1,174 @@
+package urlshort
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// migration changes the schema of a SQL database from the previous version
+// to version, in a transaction
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations are applied in order, a released migration never changes, a
+// new schema is a new migration at the end
+var migrations = []migration{
+	{1, "create the urls table", execAll(
+		`CREATE TABLE IF NOT EXISTS urls (
+			path TEXT NOT NULL,
+			url  TEXT NOT NULL
+		)`,
+	)},
+	{2, "create the clicks table", execAll(
+		`CREATE TABLE IF NOT EXISTS clicks (
+			path       TEXT NOT NULL,
+			time       TIMESTAMP NOT NULL,
+			referrer   TEXT NOT NULL,
+			user_agent TEXT NOT NULL,
+			visitor    TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS clicks_time ON clicks (time)`,
+	)},
+	{3, "key the links by domain and path", rebuildURLs},
+	{4, "record the domain of the clicks", execAll(
+		`ALTER TABLE clicks ADD COLUMN domain TEXT NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS clicks_link ON clicks (domain, path, time)`,
+	)},
+}
+
+// legacyColumns are the columns the urls tables made before the migrations
+// may lack, with their definition
+var legacyColumns = [][2]string{
+	{"domain", "TEXT NOT NULL DEFAULT ''"},
+	{"expires_at", "TIMESTAMP NULL"},
+	{"max_clicks", "INTEGER NOT NULL DEFAULT 0"},
+	{"status", "INTEGER NOT NULL DEFAULT 0"},
+	{"enabled", "BOOLEAN NOT NULL DEFAULT TRUE"},
+	{"hits", "INTEGER NOT NULL DEFAULT 0"},
+	{"created_at", "TIMESTAMP NULL"},
+}
+
+// Migrate applies the migrations db doesn't have yet, recording them in the
+// schema_migrations table, and returns the version of the schema. The
+// databases made before the migrations are brought to the last version too
+func Migrate(db *sql.DB) (int, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return 0, err
+	}
+	version, err := schemaVersion(db)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		if err := apply(db, m); err != nil {
+			return version, fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		slog.Info("applied migration", "version", m.version, "name", m.name)
+		version = m.version
+	}
+	return version, nil
+}
+
+func schemaVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+func apply(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`, m.version, m.name, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func execAll(queries ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, query := range queries {
+			if _, err := tx.Exec(query); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// rebuildURLs copies the links into a urls table keyed by domain and path,
+// the old tables are keyed by path or not keyed at all. A path stored twice
+// in an unkeyed table keeps one of its links
+func rebuildURLs(tx *sql.Tx) error {
+	if err := addMissingColumns(tx); err != nil {
+		return err
+	}
+	columns := "domain, path, url, expires_at, max_clicks, status, enabled, hits, created_at"
+	return execAll(
+		`CREATE TABLE urls_new (
+			domain     TEXT NOT NULL DEFAULT '',
+			path       TEXT NOT NULL,
+			url        TEXT NOT NULL,
+			expires_at TIMESTAMP NULL,
+			max_clicks INTEGER NOT NULL DEFAULT 0,
+			status     INTEGER NOT NULL DEFAULT 0,
+			enabled    BOOLEAN NOT NULL DEFAULT TRUE,
+			hits       INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NULL,
+			PRIMARY KEY (domain, path)
+		)`,
+		// WHERE lets SQLite parse the ON CONFLICT of an INSERT ... SELECT
+		`INSERT INTO urls_new (`+columns+`) SELECT `+columns+` FROM urls WHERE 1 = 1 ON CONFLICT DO NOTHING`,
+		`DROP TABLE urls`,
+		`ALTER TABLE urls_new RENAME TO urls`,
+	)(tx)
+}
+
+// addMissingColumns adds the legacyColumns the urls table doesn't have
+func addMissingColumns(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT * FROM urls WHERE 1 = 0`)
+	if err != nil {
+		return err
+	}
+	names, err := rows.Columns()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	have := map[string]bool{}
+	for _, name := range names {
+		have[strings.ToLower(name)] = true
+	}
+	for _, column := range legacyColumns {
+		if have[column[0]] {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE urls ADD COLUMN %s %s`, column[0], column[1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package urlshort
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema []string
+		links  int
+	}{
+		{"new", nil, 0},
+		{"unkeyed", []string{
+			// the table read by the first version of the shortener
+			`CREATE TABLE urls (path TEXT, url TEXT NOT NULL)`,
+			`INSERT INTO urls (path, url) VALUES ('/old', 'https://go.dev')`,
+			`INSERT INTO urls (path, url) VALUES ('/old', 'https://go.dev')`,
+		}, 1},
+		{"keyed by path", []string{
+			`CREATE TABLE urls (path TEXT PRIMARY KEY, url TEXT NOT NULL, max_clicks INTEGER NOT NULL DEFAULT 0)`,
+			`INSERT INTO urls (path, url, max_clicks) VALUES ('/old', 'https://go.dev', 3)`,
+		}, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "urls.sqlite"))
+			if err != nil {
+				t.Fatalf("Open received an error: %s", err.Error())
+			}
+			defer db.Close()
+			for _, query := range tc.schema {
+				if _, err := db.Exec(query); err != nil {
+					t.Fatalf("%s received an error: %s", query, err.Error())
+				}
+			}
+
+			for i := 0; i < 2; i++ {
+				if version, err := Migrate(db); err != nil || version != len(migrations) {
+					t.Fatalf("Migrate: want version %d, got %d, %v", len(migrations), version, err)
+				}
+			}
+			var applied int
+			db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied)
+			if applied != len(migrations) {
+				t.Errorf("schema_migrations: want %d migrations, got %d", len(migrations), applied)
+			}
+
+			store, err := NewSQLStore(db)
+			if err != nil {
+				t.Fatalf("NewSQLStore received an error: %s", err.Error())
+			}
+			links, err := store.List()
+			if err != nil || len(links) != tc.links {
+				t.Errorf("List(): want the old links, got %+v, %v", links, err)
+			}
+
+			// the same path on two domains is two links
+			for _, domain := range []string{"", "go.example.com"} {
+				if err := store.Put(LinkData{Domain: domain, Path: "/old", URL: "https://go.dev/old"}); err != nil {
+					t.Errorf("Put(%s/old) received an error: %s", domain, err.Error())
+				}
+			}
+			if links, _ := store.List(); len(links) != 2 {
+				t.Errorf("List(): want 2 links, got %+v", links)
+			}
+		})
+	}
+}
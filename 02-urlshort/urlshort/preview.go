@@ -0,0 +1,166 @@
+package urlshort
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	// qrSuffix asks for the QR code of a link, like /docs.qr
+	qrSuffix = ".qr"
+	// previewSuffix asks for the preview page of a link, like /docs+
+	previewSuffix = "+"
+
+	qrSize    = 256
+	qrMaxSize = 2048
+)
+
+// cutView returns the path of the link a QR code or preview path is for,
+// and which one it is
+func cutView(path string) (string, string, bool) {
+	for _, suffix := range []string{qrSuffix, previewSuffix} {
+		if link, ok := strings.CutSuffix(path, suffix); ok && link != "" && link != "/" {
+			return link, suffix, true
+		}
+	}
+	return "", "", false
+}
+
+// shortURL returns the URL of path on the host of the request
+func shortURL(r *http.Request, path string) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return (&url.URL{Scheme: scheme, Host: r.Host, Path: path}).String()
+}
+
+// serveQR writes the QR code of the short URL of path, a PNG of the size
+// query parameter or an SVG when the format query parameter is svg
+func serveQR(w http.ResponseWriter, r *http.Request, path string) {
+	size, err := queryInt(r, "size", qrSize)
+	if err != nil || size > qrMaxSize {
+		http.Error(w, fmt.Sprintf("Invalid size, expected at most %d pixels", qrMaxSize), http.StatusBadRequest)
+		return
+	}
+	q, err := qrcode.New(shortURL(r, path), qrcode.Medium)
+	if err != nil {
+		http.Error(w, "Failed to make the QR code", http.StatusInternalServerError)
+		return
+	}
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "png":
+		png, err := q.PNG(size)
+		if err != nil {
+			http.Error(w, "Failed to make the QR code", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(png)
+	case "svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(qrSVG(q, size))
+	default:
+		http.Error(w, fmt.Sprintf("Invalid format %q, expected png or svg", format), http.StatusBadRequest)
+	}
+}
+
+// qrSVG draws the modules of q as one path, scaled to size pixels
+func qrSVG(q *qrcode.QRCode, size int) []byte {
+	bitmap := q.Bitmap()
+	n := len(bitmap)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, n, n)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, n, n)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&b, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return b.Bytes()
+}
+
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="robots" content="noindex">
+  <title>{{.Short}}</title>
+</head>
+<body>
+  <h1>{{.Short}}</h1>
+  <p>Redirects to <a href="{{.URL}}" rel="nofollow noopener">{{.URL}}</a></p>
+  <dl>
+    <dt>Created</dt>
+    <dd>{{with .CreatedAt}}{{.Format "2006-01-02"}}{{else}}unknown{{end}}</dd>
+    <dt>Clicks</dt>
+    <dd>{{if .Counted}}{{.Clicks}}{{else}}not recorded{{end}}</dd>
+    {{- with .ExpiresAt}}
+    <dt>{{if $.Expired}}Expired{{else}}Expires{{end}}</dt>
+    <dd>{{.Format "2006-01-02 15:04 MST"}}</dd>
+    {{- end}}
+  </dl>
+  <img src="{{.QR}}" alt="QR code of {{.Short}}" width="200" height="200">
+</body>
+</html>
+`))
+
+// servePreview writes the preview page of link, the clicks come from the
+// ClickLog of the request, see ClickHandler, or else from log
+func servePreview(w http.ResponseWriter, r *http.Request, link LinkData, path string, log ClickLog) {
+	if l, ok := r.Context().Value(clickLogKey{}).(ClickLog); ok {
+		log = l
+	}
+	data := struct {
+		LinkData
+		Short   string
+		QR      string
+		Expired bool
+		Counted bool
+		Clicks  int
+	}{
+		LinkData: link,
+		Short:    shortURL(r, path),
+		QR:       path + qrSuffix,
+		Expired:  link.Expired(time.Now()),
+	}
+	data.URL = forwardQuery(link.URL, r.URL.RawQuery)
+	if log != nil {
+		clicks, err := log.CountClicks(link.Domain, path)
+		if err != nil {
+			metrics.storeErrors.inc("clicks")
+			slog.Error("failed to load the clicks", "path", path, "err", err)
+			http.Error(w, "Failed to load the clicks", http.StatusInternalServerError)
+			return
+		}
+		data.Counted = true
+		data.Clicks = clicks
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := previewPage.Execute(w, data); err != nil {
+		slog.Error("failed to write the preview page", "path", path, "err", err)
+	}
+}
+
+// clickLogKey is the context key of the ClickLog the clicks of a request
+// are recorded to
+type clickLogKey struct{}
+
+func withClickLog(ctx context.Context, log ClickLog) context.Context {
+	return context.WithValue(ctx, clickLogKey{}, log)
+}
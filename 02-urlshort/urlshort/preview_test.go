@@ -0,0 +1,127 @@
+package urlshort
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreHandlerQR(t *testing.T) {
+	store := NewMemoryStore(LinkData{Path: "/docs", URL: "https://go.dev/doc"})
+	handler := StoreHandler(store, http.NotFoundHandler())
+
+	tests := []struct {
+		path        string
+		status      int
+		contentType string
+	}{
+		{"/docs.qr", http.StatusOK, "image/png"},
+		{"/docs.qr?size=100", http.StatusOK, "image/png"},
+		{"/docs.qr?format=svg", http.StatusOK, "image/svg+xml"},
+		{"/docs.qr?format=gif", http.StatusBadRequest, ""},
+		{"/docs.qr?size=99999", http.StatusBadRequest, ""},
+		{"/missing.qr", http.StatusNotFound, ""},
+	}
+	for _, tc := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s: want %d, got %d (%s)", tc.path, tc.status, rec.Code, rec.Body.String())
+			continue
+		}
+		if tc.contentType != "" && rec.Header().Get("Content-Type") != tc.contentType {
+			t.Errorf("%s: want a %s, got %s", tc.path, tc.contentType, rec.Header().Get("Content-Type"))
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs.qr?size=100", nil))
+	img, err := png.Decode(rec.Body)
+	if err != nil {
+		t.Fatalf("png.Decode received an error: %s", err.Error())
+	}
+	if b := img.Bounds(); b.Dx() != 100 || b.Dy() != 100 {
+		t.Errorf("/docs.qr?size=100: want 100x100 pixels, got %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+func TestStoreHandlerPreview(t *testing.T) {
+	created := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	store := NewMemoryStore(
+		LinkData{Path: "/docs", URL: "https://go.dev/doc", CreatedAt: &created},
+		LinkData{Path: "/gh/*", URL: "https://github.com/*"},
+		LinkData{Path: "/off", URL: "https://example.com", Enabled: new(bool)},
+	)
+	store.AddClicks([]Click{{Path: "/docs", Time: created}, {Path: "/docs", Time: created}, {Path: "/gh/golang", Time: created}})
+	handler := StoreHandler(store, http.NotFoundHandler())
+
+	tests := []struct {
+		path   string
+		status int
+		want   []string
+	}{
+		{"/docs+", http.StatusOK, []string{`href="https://go.dev/doc"`, "<dd>2024-05-06</dd>", "<dd>2</dd>", `src="/docs.qr"`, "http://short.example/docs"}},
+		{"/gh/golang+", http.StatusOK, []string{`href="https://github.com/golang"`, "<dd>unknown</dd>", "<dd>1</dd>"}},
+		{"/off+", http.StatusNotFound, nil},
+		{"/missing+", http.StatusNotFound, nil},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Host = "short.example"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("%s: want %d, got %d", tc.path, tc.status, rec.Code)
+			continue
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Errorf("%s: want %q in\n%s", tc.path, want, rec.Body.String())
+			}
+		}
+	}
+}
+
+func TestPreviewClicksOfRecorder(t *testing.T) {
+	// the links of a file are previewed with the clicks of the main store
+	log := NewMemoryStore()
+	log.AddClicks([]Click{{Path: "/docs", Time: time.Now()}})
+	rec := NewRecorder(log)
+	defer rec.Close()
+
+	links := StoreHandler(NewMemoryStore(LinkData{Path: "/docs", URL: "https://go.dev/doc"}), http.NotFoundHandler())
+	w := httptest.NewRecorder()
+	ClickHandler(rec, "salt", links).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs+", nil))
+	if !strings.Contains(w.Body.String(), "<dd>1</dd>") {
+		t.Errorf("/docs+: want 1 click in\n%s", w.Body.String())
+	}
+}
+
+func TestStoresCreatedAt(t *testing.T) {
+	created := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	for name, store := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := store.Put(LinkData{Path: "/a", URL: "https://a.example.com", CreatedAt: &created}); err != nil {
+				t.Fatalf("Put received an error: %s", err.Error())
+			}
+			link, err := store.Get("", "/a")
+			if err != nil || link.CreatedAt == nil || !link.CreatedAt.Equal(created) {
+				t.Errorf("Get(/a): want created at %v, got %+v, %v", created, link, err)
+			}
+		})
+	}
+}
+
+func TestQRSVG(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/docs.qr?format=svg&size=300", nil)
+	StoreHandler(NewMemoryStore(LinkData{Path: "/docs", URL: "https://go.dev"}), http.NotFoundHandler()).ServeHTTP(rec, req)
+	body := rec.Body.Bytes()
+	if !bytes.HasPrefix(body, []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="300" height="300"`)) || !bytes.HasSuffix(body, []byte("</svg>")) {
+		t.Errorf("SVG: want a 300 pixels svg, got %s", body)
+	}
+}
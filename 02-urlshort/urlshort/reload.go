@@ -0,0 +1,157 @@
+package urlshort
+
+import (
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay groups the events of one save, editors often write a file in
+// several steps
+const reloadDelay = 100 * time.Millisecond
+
+// FileHandler is an http.Handler serving the links of a file loaded by
+// LoadFile, which can reload the file when it changes. A file that fails to
+// load keeps the previous links, the redirects counted for max_clicks are
+// kept across reloads
+type FileHandler struct {
+	path     string
+	opts     []LoadOption
+	fallback http.Handler
+	handler  atomic.Pointer[http.HandlerFunc]
+	// hits are shared by the links of every reload, so the click limits
+	// don't start over when the file changes
+	hits *hitCounts
+
+	mu      sync.Mutex // guards watcher
+	watcher *fsnotify.Watcher
+}
+
+// NewFileHandler loads the links of the file at path with opts, the paths
+// it doesn't have go to fallback
+func NewFileHandler(path string, fallback http.Handler, opts ...LoadOption) (*FileHandler, error) {
+	h := &FileHandler{
+		path:     filepath.Clean(path),
+		opts:     opts,
+		fallback: fallback,
+		hits:     &hitCounts{hits: map[string]int{}},
+	}
+	if _, err := h.Reload(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// ServeHTTP implements http.Handler
+func (h *FileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*h.handler.Load())(w, r)
+}
+
+// Reload reads the file again and swaps its links in, it returns how many
+// links were loaded. On error the previous links stay in use
+func (h *FileHandler) Reload() (int, error) {
+	links, err := LoadFile(h.path, h.opts...)
+	if err != nil {
+		return 0, err
+	}
+
+	handler := StoreHandler(fileStore{NewMemoryStore(links...), h.hits}, h.fallback)
+	h.handler.Store(&handler)
+	return len(links), nil
+}
+
+// fileStore is a MemoryStore of the links of a FileHandler counting their
+// hits with the handler
+type fileStore struct {
+	*MemoryStore
+	hits *hitCounts
+}
+
+// Hit implements HitCounter
+func (s fileStore) Hit(domain, path string) (int, error) {
+	return s.hits.hit(linkKey(domain, path)), nil
+}
+
+// hitCounts are the redirect counts of links keyed by linkKey, safe for
+// concurrent use
+type hitCounts struct {
+	mu   sync.Mutex
+	hits map[string]int
+}
+
+func (c *hitCounts) hit(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hits[key]++
+	return c.hits[key]
+}
+
+// Watch reloads the file every time it changes until Close is called
+func (h *FileHandler) Watch() error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// watch the directory, editors replace the file instead of writing it
+	if err := w.Add(filepath.Dir(h.path)); err != nil {
+		w.Close()
+		return err
+	}
+
+	h.mu.Lock()
+	h.watcher = w
+	h.mu.Unlock()
+
+	go h.watch(w)
+	return nil
+}
+
+// Close stops watching the file
+func (h *FileHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.watcher == nil {
+		return nil
+	}
+	err := h.watcher.Close()
+	h.watcher = nil
+	return err
+}
+
+func (h *FileHandler) watch(w *fsnotify.Watcher) {
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != h.path || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			timer.Reset(reloadDelay)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			slog.Warn("failed to watch the links file", "file", h.path, "err", err)
+		case <-timer.C:
+			n, err := h.Reload()
+			if err != nil {
+				slog.Error("failed to reload links, keeping the previous ones", "file", h.path, "err", err)
+				continue
+			}
+			slog.Info("reloaded links", "file", h.path, "links", n)
+		}
+	}
+}
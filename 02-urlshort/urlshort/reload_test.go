@@ -0,0 +1,93 @@
+package urlshort
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileHandlerReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.yaml")
+	write := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("WriteFile received an error: %s", err.Error())
+		}
+	}
+	status := func(h http.Handler, path string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+	// waitFor polls until the handler answers want, the reload is async
+	waitFor := func(h http.Handler, path string, want int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for status(h, path) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: want %d after the reload, got %d", path, want, status(h, path))
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	write("- path: /old\n  url: https://go.dev\n")
+	h, err := NewFileHandler(path, http.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("NewFileHandler received an error: %s", err.Error())
+	}
+	if err := h.Watch(); err != nil {
+		t.Fatalf("Watch received an error: %s", err.Error())
+	}
+	defer h.Close()
+
+	write("- path: /new\n  url: https://go.dev\n")
+	waitFor(h, "/new", http.StatusMovedPermanently)
+	if got := status(h, "/old"); got != http.StatusNotFound {
+		t.Errorf("/old: want %d after the reload, got %d", http.StatusNotFound, got)
+	}
+
+	// a broken file keeps the previous links
+	write("- path: [/broken\n")
+	time.Sleep(5 * reloadDelay)
+	if got := status(h, "/new"); got != http.StatusMovedPermanently {
+		t.Errorf("/new after a bad edit: want %d, got %d", http.StatusMovedPermanently, got)
+	}
+
+	// replacing the file like editors do is picked up too
+	tmp := path + ".tmp"
+	os.WriteFile(tmp, []byte("- path: /renamed\n  url: https://go.dev\n"), 0644)
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("Rename received an error: %s", err.Error())
+	}
+	waitFor(h, "/renamed", http.StatusMovedPermanently)
+}
+
+func TestFileHandlerReloadHits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.yaml")
+	if err := os.WriteFile(path, []byte("- path: /once\n  url: https://go.dev\n  max_clicks: 1\n"), 0644); err != nil {
+		t.Fatalf("WriteFile received an error: %s", err.Error())
+	}
+	h, err := NewFileHandler(path, http.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("NewFileHandler received an error: %s", err.Error())
+	}
+	status := func() int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/once", nil))
+		return rec.Code
+	}
+
+	if got := status(); got != http.StatusMovedPermanently {
+		t.Fatalf("first click: want %d, got %d", http.StatusMovedPermanently, got)
+	}
+	if _, err := h.Reload(); err != nil {
+		t.Fatalf("Reload received an error: %s", err.Error())
+	}
+	// the click made before the reload still counts
+	if got := status(); got != http.StatusGone {
+		t.Errorf("click after the reload: want %d, got %d", http.StatusGone, got)
+	}
+}
@@ -0,0 +1,199 @@
+package urlshort
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// PatternLister lists the pattern links of a store, the stores returned by
+// OpenStore implement it
+type PatternLister interface {
+	// Patterns returns the links whose path is a pattern
+	Patterns() ([]LinkData, error)
+}
+
+// IsPattern reports whether path has a parameter or a wildcard
+func IsPattern(path string) bool {
+	return strings.HasSuffix(path, "/*") || strings.Contains(path, "{")
+}
+
+// route is a parsed pattern, the segments of the path are literals or
+// parameters written {name}
+type route struct {
+	link     LinkData
+	segments []string
+	wildcard bool // the path ends with /*
+}
+
+func parseRoute(link LinkData) (route, error) {
+	r := route{link: link}
+	path := link.Path
+	if rest, ok := strings.CutSuffix(path, "/*"); ok {
+		r.wildcard = true
+		path = rest
+	}
+	if path != "" {
+		r.segments = strings.Split(strings.TrimPrefix(path, "/"), "/")
+	}
+
+	names := map[string]bool{}
+	for _, s := range r.segments {
+		name, isParam := paramName(s)
+		switch {
+		case strings.Contains(s, "*"):
+			return r, fmt.Errorf("link %s: * is only allowed as the last segment", link.Path)
+		case !isParam && strings.ContainsAny(s, "{}"):
+			return r, fmt.Errorf("link %s: a parameter must be a whole segment like {name}", link.Path)
+		case isParam && name == "":
+			return r, fmt.Errorf("link %s: a parameter needs a name", link.Path)
+		case isParam && names[name]:
+			return r, fmt.Errorf("link %s: parameter {%s} is used twice", link.Path, name)
+		}
+		if isParam {
+			names[name] = true
+		}
+	}
+
+	// the URL can only use what the path defines
+	target := link.URL
+	for {
+		start := strings.Index(target, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(target[start:], "}")
+		if end < 0 {
+			return r, fmt.Errorf("link %s: unclosed { in url %s", link.Path, link.URL)
+		}
+		if name := target[start+1 : start+end]; !names[name] {
+			return r, fmt.Errorf("link %s: url uses {%s} which the path doesn't define", link.Path, name)
+		}
+		target = target[start+end+1:]
+	}
+	if strings.Contains(link.URL, "*") && !r.wildcard {
+		return r, fmt.Errorf("link %s: url uses * but the path doesn't end with /*", link.Path)
+	}
+	return r, nil
+}
+
+func paramName(segment string) (string, bool) {
+	if len(segment) >= 2 && segment[0] == '{' && segment[len(segment)-1] == '}' {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+// match returns the URL the route redirects path to
+func (r route) match(path string) (string, bool) {
+	var rest string
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if r.wildcard {
+		if len(parts) < len(r.segments) {
+			return "", false
+		}
+		rest = strings.Join(parts[len(r.segments):], "/")
+		parts = parts[:len(r.segments)]
+	} else if len(parts) != len(r.segments) {
+		return "", false
+	}
+
+	var replacements []string
+	for i, s := range r.segments {
+		if name, ok := paramName(s); ok {
+			if parts[i] == "" {
+				return "", false
+			}
+			replacements = append(replacements, "{"+name+"}", url.PathEscape(parts[i]))
+		} else if parts[i] != s {
+			return "", false
+		}
+	}
+	if r.wildcard {
+		replacements = append(replacements, "*", escapePath(rest))
+	}
+	return strings.NewReplacer(replacements...).Replace(r.link.URL), true
+}
+
+// before reports whether r takes precedence over o
+func (r route) before(o route) bool {
+	if r.wildcard != o.wildcard {
+		return !r.wildcard
+	}
+	if len(r.segments) != len(o.segments) {
+		return len(r.segments) > len(o.segments)
+	}
+	for i := range r.segments {
+		_, rParam := paramName(r.segments[i])
+		_, oParam := paramName(o.segments[i])
+		if rParam != oParam {
+			return !rParam
+		}
+	}
+	return r.link.Path < o.link.Path
+}
+
+// Match returns the pattern link matching path with its URL filled in for
+// path. The path of a pattern link matches many paths:
+//
+//	/gh/*          a prefix route, * is the rest of the path after /gh/
+//	/issue/{id}    a parameter route, {id} is exactly one segment
+//
+// The URL of a pattern uses the same names, like https://github.com/* or
+// https://tracker/browse/PROJ-{id}. When several links match a path:
+//
+//  1. the exact link of the path wins
+//  2. then the parameter routes, then the prefix routes
+//  3. then the route with the most segments
+//  4. then, comparing the segments from the left, the first route having a
+//     literal segment where the other one has a parameter
+//
+// StoreHandler also adds the query string of the request to the URL
+func Match(patterns []LinkData, path string) (LinkData, bool) {
+	routes := make([]route, 0, len(patterns))
+	for _, link := range patterns {
+		// invalid patterns are refused by Validate, skip the ones stored anyway
+		if r, err := parseRoute(link); err == nil {
+			routes = append(routes, r)
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].before(routes[j])
+	})
+
+	for _, r := range routes {
+		if target, ok := r.match(path); ok {
+			link := r.link
+			link.URL = target
+			return link, true
+		}
+	}
+	return LinkData{}, false
+}
+
+// forwardQuery adds the query string of the request to target
+func forwardQuery(target, rawQuery string) string {
+	if rawQuery == "" {
+		return target
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+	if u.RawQuery == "" {
+		u.RawQuery = rawQuery
+	} else {
+		u.RawQuery += "&" + rawQuery
+	}
+	return u.String()
+}
+
+// escapePath escapes every segment of path, keeping the slashes
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,108 @@
+package urlshort
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	patterns := []LinkData{
+		{Path: "/gh/*", URL: "https://github.com/*"},
+		{Path: "/gh/{user}/*", URL: "https://github.com/{user}?tab=*"},
+		{Path: "/issue/{id}", URL: "https://tracker/browse/PROJ-{id}"},
+		{Path: "/issue/{id}/{tab}", URL: "https://tracker/browse/PROJ-{id}#{tab}"},
+		{Path: "/{team}/docs", URL: "https://docs/{team}"},
+		{Path: "/go/docs", URL: "https://go.dev/doc"},
+		{Path: "/x/{a}", URL: "https://x/{a}"},
+		{Path: "/{b}/y", URL: "https://y/{b}"},
+		{Path: "/*", URL: "https://example.com/*"},
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/gh/", "https://github.com/"},
+		{"/gh/golang", "https://github.com/golang?tab="},
+		{"/gh/golang/repositories", "https://github.com/golang?tab=repositories"},
+		{"/issue/42", "https://tracker/browse/PROJ-42"},
+		{"/issue/42/comments", "https://tracker/browse/PROJ-42#comments"},
+		{"/issue/a b", "https://tracker/browse/PROJ-a%20b"},
+		// a literal segment on the left beats a parameter
+		{"/x/y", "https://x/y"},
+		{"/infra/docs", "https://docs/infra"},
+		{"/go/docs", "https://go.dev/doc"},
+		// a parameter matches one non empty segment, the rest falls to the prefix routes
+		{"/issue/", "https://example.com/issue/"},
+		{"/anything/else", "https://example.com/anything/else"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			link, ok := Match(patterns, tc.path)
+			if !ok || link.URL != tc.want {
+				t.Errorf("Match(%s): want %s, got %s, %v", tc.path, tc.want, link.URL, ok)
+			}
+		})
+	}
+
+	if link, ok := Match(patterns[:1], "/ghost"); ok {
+		t.Errorf("Match(/ghost): want no match, got %v", link)
+	}
+}
+
+func TestValidatePatterns(t *testing.T) {
+	tests := []struct {
+		link  LinkData
+		valid bool
+	}{
+		{LinkData{Path: "/gh/*", URL: "https://github.com/*"}, true},
+		{LinkData{Path: "/u/{id}", URL: "https://x/{id}"}, true},
+		{LinkData{Path: "/u/{id}", URL: "https://x/{name}"}, false},
+		{LinkData{Path: "/u/{id}", URL: "https://x/*"}, false},
+		{LinkData{Path: "/u/{id}/{id}", URL: "https://x/{id}"}, false},
+		{LinkData{Path: "/u/PROJ-{id}", URL: "https://x/{id}"}, false},
+		{LinkData{Path: "/u/{}", URL: "https://x"}, false},
+		{LinkData{Path: "/*/x/*", URL: "https://x"}, false},
+	}
+	for _, tc := range tests {
+		if err := tc.link.Validate(); (err == nil) != tc.valid {
+			t.Errorf("Validate(%s -> %s): want valid %v, got %v", tc.link.Path, tc.link.URL, tc.valid, err)
+		}
+	}
+}
+
+func TestStoreHandlerPatterns(t *testing.T) {
+	for name, store := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, link := range []LinkData{
+				{Path: "/gh/*", URL: "https://github.com/*", Status: http.StatusFound},
+				{Path: "/gh/golang", URL: "https://go.dev"},
+				{Path: "/search/{q}", URL: "https://example.com/search?lang=en&q={q}"},
+			} {
+				if err := store.Put(link); err != nil {
+					t.Fatalf("Put received an error: %s", err.Error())
+				}
+			}
+			handler := StoreHandler(store, http.NotFoundHandler())
+
+			tests := []struct {
+				path     string
+				status   int
+				location string
+			}{
+				{"/gh/golang", http.StatusMovedPermanently, "https://go.dev"},
+				{"/gh/golang/go?tab=issues", http.StatusFound, "https://github.com/golang/go?tab=issues"},
+				{"/search/gophers?page=2", http.StatusMovedPermanently, "https://example.com/search?lang=en&q=gophers&page=2"},
+				{"/missing", http.StatusNotFound, ""},
+			}
+			for _, tc := range tests {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+				if rec.Code != tc.status || rec.Header().Get("Location") != tc.location {
+					t.Errorf("%s: want %d %s, got %d %s", tc.path, tc.status, tc.location, rec.Code, rec.Header().Get("Location"))
+				}
+			}
+		})
+	}
+}
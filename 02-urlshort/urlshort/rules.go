@@ -0,0 +1,57 @@
+package urlshort
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HitCounter counts the redirects of every link so their click limits can
+// be enforced, the stores returned by OpenStore implement it
+type HitCounter interface {
+	// Hit counts one more redirect of the link of domain and path and
+	// returns the count
+	Hit(domain, path string) (int, error)
+}
+
+// RedirectStatus returns the status code of the redirects of the link,
+// permanent unless the link sets another one
+func (l LinkData) RedirectStatus() int {
+	if l.Status == 0 {
+		return http.StatusMovedPermanently
+	}
+	return l.Status
+}
+
+// IsEnabled reports whether the link redirects, links are enabled unless
+// they say otherwise
+func (l LinkData) IsEnabled() bool {
+	return l.Enabled == nil || *l.Enabled
+}
+
+// Expired reports whether the link stopped redirecting at now
+func (l LinkData) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// Validate checks the rules of the link
+func (l LinkData) Validate() error {
+	switch l.Status {
+	case 0, http.StatusMovedPermanently, http.StatusFound,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return fmt.Errorf("link %s: invalid status %d, expected 301, 302, 307 or 308", l.Path, l.Status)
+	}
+	if l.MaxClicks < 0 {
+		return fmt.Errorf("link %s: invalid max_clicks %d", l.Path, l.MaxClicks)
+	}
+	if err := validateDomain(l.Domain); err != nil {
+		return fmt.Errorf("link %s: %v", l.Path, err)
+	}
+	if IsPattern(l.Path) {
+		if _, err := parseRoute(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
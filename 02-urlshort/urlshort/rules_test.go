@@ -0,0 +1,117 @@
+package urlshort
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStoreRules(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	disabled := false
+	link := LinkData{Path: "/rules", URL: "https://go.dev", ExpiresAt: &expiresAt, MaxClicks: 3, Status: 307, Enabled: &disabled}
+
+	for name, store := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := store.Put(link); err != nil {
+				t.Fatalf("Put received an error: %s", err.Error())
+			}
+			got, err := store.Get(link.Domain, link.Path)
+			if err != nil {
+				t.Fatalf("Get received an error: %s", err.Error())
+			}
+			if got.ExpiresAt == nil || !got.ExpiresAt.Equal(expiresAt) {
+				t.Errorf("ExpiresAt: want %v, got %v", expiresAt, got.ExpiresAt)
+			}
+			got.ExpiresAt = link.ExpiresAt
+			if !reflect.DeepEqual(got, link) {
+				t.Errorf("Get: want %+v, got %+v", link, got)
+			}
+
+			counter := store.(HitCounter)
+			for want := 1; want <= 2; want++ {
+				if hits, err := counter.Hit(link.Domain, link.Path); err != nil || hits != want {
+					t.Errorf("Hit: want %d, got %d, %v", want, hits, err)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreHandlerRules(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	disabled := false
+	store := NewMemoryStore(
+		LinkData{Path: "/expired", URL: "https://go.dev", ExpiresAt: &past},
+		LinkData{Path: "/limited", URL: "https://go.dev", MaxClicks: 2},
+		LinkData{Path: "/temporary", URL: "https://go.dev", Status: http.StatusFound},
+		LinkData{Path: "/disabled", URL: "https://go.dev", Enabled: &disabled},
+	)
+	handler := StoreHandler(store, http.NotFoundHandler())
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/expired", http.StatusGone},
+		{"/limited", http.StatusMovedPermanently},
+		{"/limited", http.StatusMovedPermanently},
+		{"/limited", http.StatusGone},
+		{"/temporary", http.StatusFound},
+		{"/disabled", http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s: want %d, got %d", tc.path, tc.status, rec.Code)
+		}
+	}
+}
+
+func TestYAMLHandlerRules(t *testing.T) {
+	handler, err := YAMLHandler([]byte(`
+- path: /temporary
+  url: https://go.dev
+  status: 307
+  expires_at: 2999-01-01T00:00:00Z
+`), http.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("YAMLHandler received an error: %s", err.Error())
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/temporary", nil))
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Errorf("/temporary: want %d, got %d", http.StatusTemporaryRedirect, rec.Code)
+	}
+
+	_, err = JSONHandler([]byte(`[{"path": "/bad", "url": "https://go.dev", "status": 200}]`), http.NotFoundHandler())
+	if err == nil {
+		t.Errorf("JSONHandler with status 200: want an error, got nil")
+	}
+}
+
+func TestSQLStoreAddsRuleColumns(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "old.sqlite"))
+	if err != nil {
+		t.Fatalf("Open received an error: %s", err.Error())
+	}
+	defer db.Close()
+
+	// the urls table as it was before the rules
+	db.Exec(`CREATE TABLE urls (path TEXT PRIMARY KEY, url TEXT NOT NULL)`)
+	db.Exec(`INSERT INTO urls (path, url) VALUES ('/old', 'https://go.dev')`)
+
+	store, err := NewSQLStore(db)
+	if err != nil {
+		t.Fatalf("NewSQLStore received an error: %s", err.Error())
+	}
+	link, err := store.Get("", "/old")
+	if err != nil || link.URL != "https://go.dev" || !link.IsEnabled() || link.RedirectStatus() != http.StatusMovedPermanently {
+		t.Errorf("Get(/old): want an enabled 301 link, got %+v, %v", link, err)
+	}
+}
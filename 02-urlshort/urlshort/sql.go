@@ -0,0 +1,216 @@
+package urlshort
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SQLStore is a Store keeping the links in the urls table of a SQLite or
+// Postgres database. The queries stick to the SQL both of them understand
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore returns a SQLStore using db, migrating its schema to the last
+// version first, see Migrate
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	if _, err := Migrate(db); err != nil {
+		return nil, err
+	}
+	return &SQLStore{db: db}, nil
+}
+
+// SchemaVersion returns the version of the last migration applied to the
+// database
+func (s *SQLStore) SchemaVersion() (int, error) {
+	return schemaVersion(s.db)
+}
+
+const selectLinks = `SELECT domain, path, url, expires_at, max_clicks, status, enabled, created_at FROM urls`
+
+// scanLink reads a row of selectLinks
+func scanLink(row interface{ Scan(...any) error }) (LinkData, error) {
+	var (
+		link      LinkData
+		expiresAt sql.NullTime
+		createdAt sql.NullTime
+		enabled   bool
+	)
+	if err := row.Scan(&link.Domain, &link.Path, &link.URL, &expiresAt, &link.MaxClicks, &link.Status, &enabled, &createdAt); err != nil {
+		return link, err
+	}
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.Time
+	}
+	if createdAt.Valid {
+		link.CreatedAt = &createdAt.Time
+	}
+	if !enabled {
+		link.Enabled = &enabled
+	}
+	return link, nil
+}
+
+// Get implements Store
+func (s *SQLStore) Get(domain, path string) (LinkData, error) {
+	link, err := scanLink(s.db.QueryRow(selectLinks+` WHERE domain = $1 AND path = $2`, domain, path))
+	if errors.Is(err, sql.ErrNoRows) {
+		return link, ErrNotFound
+	}
+	return link, err
+}
+
+// Put implements Store
+func (s *SQLStore) Put(link LinkData) error {
+	_, err := s.db.Exec(`INSERT INTO urls (domain, path, url, expires_at, max_clicks, status, enabled, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (domain, path) DO UPDATE SET url = excluded.url, expires_at = excluded.expires_at,
+			max_clicks = excluded.max_clicks, status = excluded.status, enabled = excluded.enabled, created_at = excluded.created_at`,
+		link.Domain, link.Path, link.URL, nullTime(link.ExpiresAt), link.MaxClicks, link.Status, link.IsEnabled(), nullTime(link.CreatedAt))
+	return err
+}
+
+// nullTime stores an optional time in UTC
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// Delete implements Store
+func (s *SQLStore) Delete(domain, path string) error {
+	res, err := s.db.Exec(`DELETE FROM urls WHERE domain = $1 AND path = $2`, domain, path)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List implements Store
+func (s *SQLStore) List() ([]LinkData, error) {
+	rows, err := s.db.Query(selectLinks + ` ORDER BY domain, path`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// iterate for all rows
+	var links []LinkData
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// Patterns implements PatternLister
+func (s *SQLStore) Patterns() ([]LinkData, error) {
+	rows, err := s.db.Query(selectLinks + ` WHERE path LIKE '%/*' OR path LIKE '%{%'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []LinkData
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		if IsPattern(link.Path) {
+			links = append(links, link)
+		}
+	}
+	return links, rows.Err()
+}
+
+// Hit implements HitCounter
+func (s *SQLStore) Hit(domain, path string) (int, error) {
+	var hits int
+	err := s.db.QueryRow(`UPDATE urls SET hits = hits + 1 WHERE domain = $1 AND path = $2 RETURNING hits`, domain, path).Scan(&hits)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return hits, err
+}
+
+// AddClicks implements ClickLog, the batch is inserted in one transaction
+func (s *SQLStore) AddClicks(clicks []Click) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO clicks (domain, path, time, referrer, user_agent, visitor) VALUES ($1, $2, $3, $4, $5, $6)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, c := range clicks {
+		if _, err := stmt.Exec(c.Domain, c.Path, c.Time.UTC(), c.Referrer, c.UserAgent, c.Visitor); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CountClicks implements ClickLog
+func (s *SQLStore) CountClicks(domain, path string) (int, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM clicks WHERE domain = $1 AND path = $2`, domain, path).Scan(&n)
+	return n, err
+}
+
+// Clicks implements ClickLog
+func (s *SQLStore) Clicks(domain, path string, since time.Time) ([]Click, error) {
+	query := `SELECT domain, path, time, referrer, user_agent, visitor FROM clicks WHERE time >= $1`
+	args := []any{since.UTC()}
+	if domain != "" || path != "" {
+		query += ` AND domain = $2`
+		args = append(args, domain)
+	}
+	if path != "" {
+		query += ` AND path = $3`
+		args = append(args, path)
+	}
+
+	rows, err := s.db.Query(query+` ORDER BY time`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clicks []Click
+	for rows.Next() {
+		var c Click
+		if err := rows.Scan(&c.Domain, &c.Path, &c.Time, &c.Referrer, &c.UserAgent, &c.Visitor); err != nil {
+			return nil, err
+		}
+		clicks = append(clicks, c)
+	}
+	return clicks, rows.Err()
+}
+
+// Close implements Store
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// Ping implements Pinger
+func (s *SQLStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
@@ -0,0 +1,105 @@
+package urlshort
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openStores(t *testing.T) map[string]Store {
+	dir := t.TempDir()
+	stores := map[string]Store{}
+	for name, dsn := range map[string]string{
+		"memory": "memory:",
+		"bolt":   "bolt://" + filepath.Join(dir, "urls.bolt"),
+		"sqlite": "sqlite://" + filepath.Join(dir, "urls.sqlite"),
+	} {
+		store, err := OpenStore(dsn)
+		if err != nil {
+			t.Fatalf("OpenStore(%s) received an error: %s", dsn, err.Error())
+		}
+		t.Cleanup(func() { store.Close() })
+		stores[name] = store
+	}
+	return stores
+}
+
+func TestStores(t *testing.T) {
+	for name, store := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Get("", "/missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get(/missing): want ErrNotFound, got %v", err)
+			}
+
+			for _, link := range []LinkData{
+				{Path: "/b", URL: "https://b.example.com"},
+				{Path: "/a", URL: "https://a.example.com"},
+				{Path: "/b", URL: "https://b2.example.com"},
+			} {
+				if err := store.Put(link); err != nil {
+					t.Fatalf("Put(%v) received an error: %s", link, err.Error())
+				}
+			}
+
+			link, err := store.Get("", "/b")
+			if err != nil || link.URL != "https://b2.example.com" {
+				t.Errorf("Get(/b): want the replaced link, got %v, %v", link, err)
+			}
+
+			links, err := store.List()
+			if err != nil || len(links) != 2 || links[0].Path != "/a" {
+				t.Errorf("List(): want [/a /b], got %v, %v", links, err)
+			}
+
+			if err := store.Delete("", "/a"); err != nil {
+				t.Errorf("Delete(/a) received an error: %s", err.Error())
+			}
+			if err := store.Delete("", "/a"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Delete(/a) twice: want ErrNotFound, got %v", err)
+			}
+
+			// the same path on another domain is another link
+			if err := store.Put(LinkData{Domain: "go.example.com", Path: "/b", URL: "https://go.dev"}); err != nil {
+				t.Fatalf("Put(go.example.com/b) received an error: %s", err.Error())
+			}
+			link, err = store.Get("", "/b")
+			if err != nil || link.URL != "https://b2.example.com" {
+				t.Errorf("Get(/b): want the link without a domain, got %v, %v", link, err)
+			}
+			link, err = store.Get("go.example.com", "/b")
+			if err != nil || link.URL != "https://go.dev" || link.Domain != "go.example.com" {
+				t.Errorf("Get(go.example.com/b): want the link of the domain, got %v, %v", link, err)
+			}
+			if err := store.Delete("go.example.com", "/b"); err != nil {
+				t.Errorf("Delete(go.example.com/b) received an error: %s", err.Error())
+			}
+			if _, err := store.Get("", "/b"); err != nil {
+				t.Errorf("Get(/b) after deleting go.example.com/b received an error: %s", err.Error())
+			}
+		})
+	}
+}
+
+func TestStoreHandler(t *testing.T) {
+	store := NewMemoryStore()
+	fallback := http.NotFoundHandler()
+	handler := StoreHandler(store, fallback)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/new", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown path: want %d, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	// a link added after the handler is built works right away
+	store.Put(LinkData{Path: "/new", URL: "https://example.com"})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/new", nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "https://example.com" {
+		t.Errorf("new link: want a redirect to https://example.com, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
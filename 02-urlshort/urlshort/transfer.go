@@ -0,0 +1,152 @@
+package urlshort
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrConflict is returned by ImportLinks when a link is already stored and
+// the conflicts fail the import
+var ErrConflict = errors.New("urlshort: link already stored")
+
+// Conflict is what ImportLinks does with a link already stored
+type Conflict string
+
+// The ways to handle a link already stored
+const (
+	ConflictSkip      Conflict = "skip"      // keep the stored link
+	ConflictOverwrite Conflict = "overwrite" // replace the stored link
+	ConflictFail      Conflict = "fail"      // import nothing
+)
+
+// ParseConflict returns the Conflict named s
+func ParseConflict(s string) (Conflict, error) {
+	switch c := Conflict(s); c {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+		return c, nil
+	}
+	return "", fmt.Errorf("invalid conflict %q, expected skip, overwrite or fail", s)
+}
+
+// ImportResult counts what ImportLinks did with the links
+type ImportResult struct {
+	Added    int
+	Replaced int
+	Skipped  int
+}
+
+// ImportLinks puts links in store, the links already stored are handled as
+// conflict says. With ConflictFail nothing is stored when one of the links
+// is, the error wraps ErrConflict and names the links
+func ImportLinks(store Store, links []LinkData, conflict Conflict) (ImportResult, error) {
+	var result ImportResult
+	stored := make([]*LinkData, len(links))
+	var conflicts []string
+	for i, link := range links {
+		old, err := store.Get(link.Domain, link.Path)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		stored[i] = &old
+		conflicts = append(conflicts, link.key())
+	}
+	if conflict == ConflictFail && len(conflicts) > 0 {
+		return result, fmt.Errorf("%w: %v", ErrConflict, conflicts)
+	}
+
+	for i, link := range links {
+		if old := stored[i]; old != nil {
+			if conflict == ConflictSkip {
+				result.Skipped++
+				continue
+			}
+			if link.CreatedAt == nil {
+				link.CreatedAt = old.CreatedAt
+			}
+		}
+		if err := store.Put(link); err != nil {
+			return result, err
+		}
+		if stored[i] != nil {
+			result.Replaced++
+		} else {
+			result.Added++
+		}
+	}
+	return result, nil
+}
+
+// WriteLinks writes links to w in format, in the form LoadLinks reads
+func WriteLinks(w io.Writer, links []LinkData, format string) error {
+	if links == nil {
+		links = []LinkData{}
+	}
+	switch format {
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(links); err != nil {
+			return err
+		}
+		return enc.Close()
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(links)
+	case FormatTOML:
+		return toml.NewEncoder(w).Encode(struct {
+			Links []LinkData `toml:"links"`
+		}{links})
+	case FormatCSV:
+		return writeCSV(w, links)
+	}
+	return fmt.Errorf("unknown links format %q", format)
+}
+
+// writeCSV writes a column per field of linkFields, the unset fields are
+// empty cells
+func writeCSV(w io.Writer, links []LinkData) error {
+	cw := csv.NewWriter(w)
+	cw.Write(linkFields)
+	for _, link := range links {
+		cells := map[string]string{
+			"path":   link.Path,
+			"url":    link.URL,
+			"domain": link.Domain,
+		}
+		if link.ExpiresAt != nil {
+			cells["expires_at"] = link.ExpiresAt.Format(time.RFC3339)
+		}
+		if link.CreatedAt != nil {
+			cells["created_at"] = link.CreatedAt.Format(time.RFC3339)
+		}
+		if link.MaxClicks != 0 {
+			cells["max_clicks"] = strconv.Itoa(link.MaxClicks)
+		}
+		if link.Status != 0 {
+			cells["status"] = strconv.Itoa(link.Status)
+		}
+		if link.Enabled != nil {
+			cells["enabled"] = strconv.FormatBool(*link.Enabled)
+		}
+
+		record := make([]string, len(linkFields))
+		for i, field := range linkFields {
+			record[i] = cells[field]
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,91 @@
+package urlshort
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteLinks(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	createdAt := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	disabled := false
+	links := []LinkData{
+		{Path: "/a", URL: "https://a.example.com"},
+		{Path: "/b", URL: "https://b.example.com", ExpiresAt: &expiresAt, MaxClicks: 5, Status: 302, Enabled: &disabled, CreatedAt: &createdAt},
+		{Domain: "go.example.com", Path: "/issue/{id}", URL: "https://go.dev/issue/{id}"},
+	}
+
+	for _, format := range []string{FormatYAML, FormatJSON, FormatTOML, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WriteLinks(&b, links, format); err != nil {
+				t.Fatalf("WriteLinks received an error: %s", err.Error())
+			}
+			got, err := LoadLinks("", b.Bytes(), OptionFormat(format))
+			if err != nil {
+				t.Fatalf("LoadLinks received an error: %s\n%s", err.Error(), b.String())
+			}
+			for _, link := range got {
+				for _, t := range []*time.Time{link.ExpiresAt, link.CreatedAt} {
+					if t != nil {
+						*t = t.UTC()
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, links) {
+				t.Errorf("LoadLinks(WriteLinks()): want %+v, got %+v\n%s", links, got, b.String())
+			}
+		})
+	}
+
+	if err := WriteLinks(&bytes.Buffer{}, links, "xml"); err == nil {
+		t.Errorf("WriteLinks(xml): want an error, got nil")
+	}
+}
+
+func TestImportLinks(t *testing.T) {
+	createdAt := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	links := []LinkData{
+		{Path: "/a", URL: "https://new.example.com/a"},
+		{Path: "/b", URL: "https://new.example.com/b"},
+	}
+
+	tests := []struct {
+		conflict Conflict
+		want     ImportResult
+		wantURL  string
+		wantErr  error
+	}{
+		{ConflictSkip, ImportResult{Added: 1, Skipped: 1}, "https://old.example.com/a", nil},
+		{ConflictOverwrite, ImportResult{Added: 1, Replaced: 1}, "https://new.example.com/a", nil},
+		{ConflictFail, ImportResult{}, "https://old.example.com/a", ErrConflict},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.conflict), func(t *testing.T) {
+			store := NewMemoryStore(LinkData{Path: "/a", URL: "https://old.example.com/a", CreatedAt: &createdAt})
+			result, err := ImportLinks(store, links, tc.conflict)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ImportLinks: want error %v, got %v", tc.wantErr, err)
+			}
+			if result != tc.want {
+				t.Errorf("ImportLinks: want %+v, got %+v", tc.want, result)
+			}
+
+			link, _ := store.Get("", "/a")
+			if link.URL != tc.wantURL || link.CreatedAt == nil || !link.CreatedAt.Equal(createdAt) {
+				t.Errorf("Get(/a): want %s created at %v, got %+v", tc.wantURL, createdAt, link)
+			}
+			// a failed import stores nothing
+			if _, err := store.Get("", "/b"); (err == nil) != (tc.wantErr == nil) {
+				t.Errorf("Get(/b): want it stored only when the import succeeds, got %v", err)
+			}
+		})
+	}
+
+	if _, err := ParseConflict("merge"); err == nil {
+		t.Errorf("ParseConflict(merge): want an error, got nil")
+	}
+}
@@ -1,13 +1,155 @@
 package urlshort
 
 import (
-	"encoding/json"
-	"fmt"
+	"errors"
+	"log/slog"
 	"net/http"
-
-	"gopkg.in/yaml.v3"
+	"strconv"
+	"time"
 )
 
+// LinkData is a short path and the URL it redirects to, the other fields
+// are optional rules of the redirect
+type LinkData struct {
+	Path string `json:"path" yaml:"path" toml:"path"`
+	URL  string `json:"url" yaml:"url" toml:"url"`
+	// ExpiresAt is when the link stops redirecting and answers 410 Gone
+	ExpiresAt *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty" toml:"expires_at"`
+	// MaxClicks is how many redirects are made before the link answers 410
+	// Gone, 0 for no limit
+	MaxClicks int `json:"max_clicks,omitempty" yaml:"max_clicks,omitempty" toml:"max_clicks,omitzero"`
+	// Status is the redirect status code: 301 (the default), 302, 307 or 308
+	Status int `json:"status,omitempty" yaml:"status,omitempty" toml:"status,omitzero"`
+	// Enabled turns the link off when false, the path then goes to the
+	// fallback like an unknown one
+	Enabled *bool `json:"enabled,omitempty" yaml:"enabled,omitempty" toml:"enabled"`
+	// Domain is the host the link is served on, like go.example.com, the
+	// links without one are served on every host
+	Domain string `json:"domain,omitempty" yaml:"domain,omitempty" toml:"domain,omitempty"`
+	// CreatedAt is when the link was created through the API, shown on its
+	// preview page
+	CreatedAt *time.Time `json:"created_at,omitempty" yaml:"created_at,omitempty" toml:"created_at"`
+}
+
+// StoreHandler will return an http.HandlerFunc that looks the
+// request host and path up in store on every request, the link
+// of the domain of the host first then the link of every domain,
+// and redirects to the stored URL, so links added to the store
+// while the server runs work without a restart. If the path is
+// not stored or the link is disabled, then the fallback
+// http.Handler will be called instead. Expired links and links
+// out of clicks answer 410 Gone, the clicks are only limited when
+// store is a HitCounter. When store is a PatternLister, paths
+// without their own link are matched against the pattern links,
+// see Match.
+//
+// The path of a link followed by .qr, like /docs.qr, answers the
+// QR code of its short URL, a PNG or an SVG with ?format=svg. The
+// path followed by +, like /docs+, answers a page previewing the
+// link with its destination, creation date and clicks.
+func StoreHandler(store Store, fallback http.Handler) http.HandlerFunc {
+	counter, _ := store.(HitCounter)
+	lister, _ := store.(PatternLister)
+	clicks, _ := store.(ClickLog)
+	return func(w http.ResponseWriter, r *http.Request) {
+		domain := Domain(r.Host)
+		link, err := lookup(store, lister, domain, r.URL.Path)
+		// a path without its own link can be the QR code or preview of
+		// one, before the pattern links like /gh/* take it
+		path, view := r.URL.Path, ""
+		if p, v, ok := cutView(r.URL.Path); ok && (errors.Is(err, ErrNotFound) || err == nil && IsPattern(link.Path)) {
+			if l, e := lookup(store, lister, domain, p); !errors.Is(e, ErrNotFound) {
+				link, err, path, view = l, e, p, v
+			}
+		}
+		if errors.Is(err, ErrNotFound) || err == nil && !link.IsEnabled() {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			metrics.storeErrors.inc("lookup")
+			slog.Error("failed to look the link up", "host", r.Host, "path", r.URL.Path, "err", err)
+			http.Error(w, "Failed to look the link up", http.StatusInternalServerError)
+			return
+		}
+
+		switch view {
+		case qrSuffix:
+			serveQR(w, r, path)
+			return
+		case previewSuffix:
+			servePreview(w, r, link, path, clicks)
+			return
+		}
+
+		if link.Expired(time.Now()) {
+			metrics.gone.inc("expired")
+			http.Error(w, "This link has expired", http.StatusGone)
+			return
+		}
+		if link.MaxClicks > 0 && counter != nil {
+			hits, err := counter.Hit(link.Domain, link.Path)
+			if err != nil {
+				metrics.storeErrors.inc("hit")
+				slog.Error("failed to count the click", "domain", link.Domain, "path", link.Path, "err", err)
+				http.Error(w, "Failed to count the click", http.StatusInternalServerError)
+				return
+			}
+			if hits > link.MaxClicks {
+				metrics.gone.inc("max_clicks")
+				http.Error(w, "This link has reached its click limit", http.StatusGone)
+				return
+			}
+		}
+
+		setClickDomain(r.Context(), link.Domain)
+		status := link.RedirectStatus()
+		metrics.redirects.inc(strconv.Itoa(status))
+		slog.Debug("redirect", "domain", link.Domain, "path", r.URL.Path, "status", status)
+		http.Redirect(w, r, forwardQuery(link.URL, r.URL.RawQuery), status)
+	}
+}
+
+// lookup returns the link of domain and path: the exact link or else the
+// best enabled pattern link of the domain, then the same for the links of
+// every domain
+func lookup(store Store, lister PatternLister, domain, path string) (LinkData, error) {
+	var patterns []LinkData
+	domains := []string{domain, ""}
+	if domain == "" {
+		domains = domains[1:]
+	}
+
+	for _, d := range domains {
+		link, err := store.Get(d, path)
+		if err == nil && !IsPattern(link.Path) {
+			return link, nil
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return LinkData{}, err
+		}
+		if lister == nil {
+			continue
+		}
+
+		if patterns == nil {
+			if patterns, err = lister.Patterns(); err != nil {
+				return LinkData{}, err
+			}
+		}
+		var candidates []LinkData
+		for _, p := range patterns {
+			if p.Domain == d && p.IsEnabled() {
+				candidates = append(candidates, p)
+			}
+		}
+		if link, ok := Match(candidates, path); ok {
+			return link, nil
+		}
+	}
+	return LinkData{}, ErrNotFound
+}
+
 // MapHandler will return an http.HandlerFunc (which also
 // implements http.Handler) that will attempt to map any
 // paths (keys in the map) to their corresponding URL (values
@@ -17,11 +159,9 @@ import (
 func MapHandler(pathsToUrls map[string]string, fallback http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
-		fmt.Printf("Path: %v\n", path)
-		fmt.Println(pathsToUrls)
 		longURL, ok := pathsToUrls[path]
 		if !ok {
-			fmt.Println("Cannot file path, redirecting to default error page")
+			slog.Debug("no link, passing to the fallback", "path", path)
 			// couldn't find the request's path in the map
 			// pass it to next middleware pipe
 			fallback.ServeHTTP(w, r)
@@ -44,51 +184,38 @@ func MapHandler(pathsToUrls map[string]string, fallback http.Handler) http.Handl
 //
 //   - path: /some-path
 //     url: https://www.some-url.com/demo
+//     # optional rules, see LinkData
+//     expires_at: 2030-01-01T00:00:00Z
+//     max_clicks: 100
+//     status: 302
+//     enabled: true
+//   - path: /gh/*
+//     url: https://github.com/*
+//   - path: /issue/{id}
+//     url: https://tracker/browse/PROJ-{id}
 //
-// The only errors that can be returned all related to having
-// invalid YAML data.
+// The patterns of the paths are described with Match. The links
+// are checked by LoadLinks, the only errors that can be returned
+// are its LinkErrors.
 //
 // See MapHandler to create a similar http.HandlerFunc via
 // a mapping of paths to urls.
-
-type LinkData struct {
-	Path, URL string
-}
-
 func YAMLHandler(yml []byte, fallback http.Handler) (http.HandlerFunc, error) {
-	// parse yaml data into map of (maps of key and value)
-	// parsedYamlData := []map[string]string{}
-	var parsedYamlData = []LinkData{}
-	err := yaml.Unmarshal([]byte(yml), &parsedYamlData)
-	if err != nil {
-		return nil, err
-	}
-	fmt.Printf("%+v\n", parsedYamlData)
-
-	// build pathsToUrls from parsed yaml data to be passed into map handler
-	pathsToUrls := map[string]string{}
-	for _, yamlEntry := range parsedYamlData {
-		pathsToUrls[yamlEntry.Path] = yamlEntry.URL
-	}
+	return linksHandler(yml, FormatYAML, fallback)
+}
 
-	return MapHandler(pathsToUrls, fallback), nil
+// JSONHandler is like YAMLHandler, for a JSON array of links
+// with the same fields.
+func JSONHandler(jsn []byte, fallback http.Handler) (http.HandlerFunc, error) {
+	return linksHandler(jsn, FormatJSON, fallback)
 }
 
-func JSONHandler(yml []byte, fallback http.Handler) (http.HandlerFunc, error) {
-	// parse json data into map of (maps of key and value)
-	// parsedJsonData := []map[string]string{}
-	var parsedJsonData = []LinkData{}
-	err := json.Unmarshal([]byte(yml), &parsedJsonData)
+// linksHandler loads the links with LoadLinks and serves them like
+// StoreHandler, counting their clicks in memory
+func linksHandler(data []byte, format string, fallback http.Handler) (http.HandlerFunc, error) {
+	links, err := LoadLinks("", data, OptionFormat(format))
 	if err != nil {
 		return nil, err
 	}
-	fmt.Printf("%+v\n", parsedJsonData)
-
-	// build pathsToUrls from parsed yaml data to be passed into map handler
-	pathsToUrls := map[string]string{}
-	for _, yamlEntry := range parsedJsonData {
-		pathsToUrls[yamlEntry.Path] = yamlEntry.URL
-	}
-
-	return MapHandler(pathsToUrls, fallback), nil
+	return StoreHandler(NewMemoryStore(links...), fallback), nil
 }
@@ -0,0 +1,104 @@
+package cyoa
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// stateCookie is the cookie keeping the State of a reader of the web mode
+const stateCookie = "cyoa_state"
+
+// errBadSignature is returned for a state not signed with the key
+var errBadSignature = errors.New("cyoa: invalid state signature")
+
+// maxStateSize is the most bytes a State encoded by EncodeState takes,
+// browsers drop the cookies over 4096 bytes with their name and attributes
+const maxStateSize = 3800
+
+// fitHistory drops the oldest states of the history of st until st encodes
+// to at most maxStateSize bytes, or it has no history left
+func fitHistory(st State) State {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return st
+	}
+	signature := 1 + base64.RawURLEncoding.EncodedLen(sha256.Size)
+	size := len(data)
+	for len(st.History) > 0 && base64.RawURLEncoding.EncodedLen(size)+signature > maxStateSize {
+		// a state takes its JSON and a comma, or more for the last one
+		prev, _ := json.Marshal(st.History[0])
+		size -= len(prev) + 1
+		st.History = st.History[1:]
+	}
+	return st
+}
+
+// EncodeState returns st as a string signed with key, readers can see
+// their state but not change it
+func EncodeState(st State, key []byte) (string, error) {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + sign(payload, key), nil
+}
+
+// DecodeState returns the State encoded by EncodeState with key
+func DecodeState(s string, key []byte) (State, error) {
+	payload, signature, ok := strings.Cut(s, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(sign(payload, key))) {
+		return State{}, errBadSignature
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return State{}, err
+	}
+	var st State
+	err = json.Unmarshal(data, &st)
+	return st, err
+}
+
+func sign(payload string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// readState returns the state in the cookie of r, false when there is none
+// or it isn't signed with key
+func readState(r *http.Request, key []byte) (State, bool) {
+	c, err := r.Cookie(stateCookie)
+	if err != nil {
+		return State{}, false
+	}
+	st, err := DecodeState(c.Value, key)
+	if err != nil {
+		return State{}, false
+	}
+	if st.Vars == nil {
+		st.Vars = map[string]int{}
+	}
+	return st, true
+}
+
+// writeState sets the cookie of st for the pages under path
+func writeState(w http.ResponseWriter, st State, path string, key []byte) error {
+	value, err := EncodeState(st, key)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    value,
+		Path:     path,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
@@ -0,0 +1,140 @@
+package cyoa
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// NewHandler will return an http.Handler serving the stories of lib, the
+// index of the stories rendered with index and their arcs rendered with
+// tmpl, with the Page of the reader. The State of the reader of each story
+// is kept in a cookie signed with key, and changed by the links of the
+// page:
+//
+//	/                         the index of the stories
+//	/gopher/                  go to the arc of the reader of the story gopher
+//	/gopher/intro             the arc intro of the story gopher
+//	/gopher/intro?choice=1    pick the option 1 of the arc intro
+//	/gopher/?back=2           go back 2 choices, or 1 without a number
+//	/gopher/?restart          start the story over
+//	/gopher/?save=...         restore the state of the SaveURL of a page
+//
+// The arc of the URL is always the one of the reader, the other arcs and
+// a choice for another arc, like from an old page, go back to it. The
+// save URLs are signed with key too, readers can share them but not make
+// up their own
+func NewHandler(lib *Library, index, tmpl *template.Template, key []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			if err := index.Execute(w, lib.Index()); err != nil {
+				http.Error(w, fmt.Sprintf("Error while execute template: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		name, arcName, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		story, found := lib.Story(name)
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		base := "/" + url.PathEscape(name) + "/"
+		if !ok {
+			http.Redirect(w, r, base, http.StatusMovedPermanently)
+			return
+		}
+		serveStory(w, r, story, base, arcName, tmpl, storyKey(key, name))
+	})
+}
+
+// storyKey returns the key signing the states of the story called name, so
+// the states of a story are invalid in the others
+func storyKey(key []byte, name string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name))
+	return mac.Sum(nil)
+}
+
+// serveStory serves the arc arcName of story, at base
+func serveStory(w http.ResponseWriter, r *http.Request, story Story, base, arcName string, tmpl *template.Template, key []byte) {
+	st, ok := readState(r, key)
+	if _, known := story.Arcs[st.Arc]; !ok || !known {
+		st = story.NewState()
+	}
+	// the story may have been reloaded with new variables since
+	st = story.Resume(st)
+
+	query := r.URL.Query()
+	switch {
+	case query.Has("restart"):
+		st = story.NewState()
+	case query.Has("save"):
+		saved, err := DecodeState(query.Get("save"), key)
+		if _, known := story.Arcs[saved.Arc]; err != nil || !known {
+			http.Error(w, "Invalid save, it may be for another story or server", http.StatusBadRequest)
+			return
+		}
+		st = story.Resume(saved)
+	case query.Has("back"):
+		n, err := strconv.Atoi(query.Get("back"))
+		if err != nil {
+			n = 1
+		}
+		st = st.Back(n)
+	case query.Has("choice") && arcName == st.Arc:
+		choice, err := strconv.Atoi(query.Get("choice"))
+		if err != nil {
+			break
+		}
+		st, err = story.Choose(st, choice)
+		if err != nil && !errors.Is(err, ErrInvalidChoice) {
+			http.Error(w, fmt.Sprintf("Error while choosing: %v", err), http.StatusInternalServerError)
+			return
+		}
+	case arcName == st.Arc && len(query) == 0:
+		servePage(w, r, story, st, base, tmpl, key)
+		return
+	}
+
+	// the changes and stale links go back to the page of the reader
+	if err := writeState(w, st, base, key); err != nil {
+		http.Error(w, fmt.Sprintf("Error while saving the state: %v", err), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, base+url.PathEscape(st.Arc), http.StatusSeeOther)
+}
+
+func servePage(w http.ResponseWriter, r *http.Request, story Story, st State, base string, tmpl *template.Template, key []byte) {
+	page, err := story.Page(st)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error while rendering the arc: %v", err), http.StatusInternalServerError)
+		return
+	}
+	save, err := EncodeState(st, key)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error while saving the state: %v", err), http.StatusInternalServerError)
+		return
+	}
+	page.Base = base
+	page.SaveURL = saveURL(r, base, save)
+	if err := tmpl.Execute(w, page); err != nil {
+		http.Error(w, fmt.Sprintf("Error while execute template: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// saveURL returns the URL restoring the state encoded as save on the host
+// of the request
+func saveURL(r *http.Request, base, save string) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + base + "?save=" + save
+}
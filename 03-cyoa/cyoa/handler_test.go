@@ -0,0 +1,204 @@
+package cyoa
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openShop returns a library of shopStory as the story shop
+func openShop(t *testing.T) *Library {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "shop.json"), []byte(shopStory), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := OpenLibrary(dir)
+	if err != nil {
+		t.Fatalf("OpenLibrary received an error: %s", err.Error())
+	}
+	return lib
+}
+
+func TestHandler(t *testing.T) {
+	lib := openShop(t)
+	index := template.Must(template.New("index").Parse(`{{range .}}{{.Name}}: {{.Title}}{{end}}`))
+	tmpl := template.Must(template.New("arc").Parse(
+		`{{range .Story}}{{.}}{{end}}{{range .Options}}|{{.Index}} {{.Text}}{{end}}`))
+	key := []byte("secret")
+	handler := NewHandler(lib, index, tmpl, key)
+
+	var cookies []*http.Cookie
+	get := func(target string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if set := w.Result().Cookies(); len(set) > 0 {
+			cookies = set
+		}
+		return w
+	}
+
+	cases := []struct {
+		target   string
+		status   int
+		location string
+		body     string
+	}{
+		{target: "/", status: http.StatusOK, body: "shop: shop"},
+		{target: "/nowhere/", status: http.StatusNotFound},
+		{target: "/shop", status: http.StatusMovedPermanently, location: "/shop/"},
+		{target: "/shop/", status: http.StatusSeeOther, location: "/shop/intro"},
+		{target: "/shop/intro", status: http.StatusOK, body: "You have 3 coins.|0 Buy a ticket|2 Walk home"},
+		{target: "/shop/intro?choice=0", status: http.StatusSeeOther, location: "/shop/intro"},
+		{target: "/shop/intro", status: http.StatusOK, body: "You have 1 coins and a ticket.|1 Board the train|2 Walk home"},
+		// not available any more
+		{target: "/shop/intro?choice=0", status: http.StatusSeeOther, location: "/shop/intro"},
+		{target: "/shop/intro?choice=1", status: http.StatusSeeOther, location: "/shop/train"},
+		// a stale link of the intro page
+		{target: "/shop/intro?choice=2", status: http.StatusSeeOther, location: "/shop/train"},
+		{target: "/shop/home", status: http.StatusSeeOther, location: "/shop/train"},
+		{target: "/shop/train", status: http.StatusOK},
+		{target: "/shop/?restart", status: http.StatusSeeOther, location: "/shop/intro"},
+		{target: "/shop/intro", status: http.StatusOK, body: "You have 3 coins.|0 Buy a ticket|2 Walk home"},
+		{target: "/shop/intro?choice=0", status: http.StatusSeeOther, location: "/shop/intro"},
+		{target: "/shop/intro?choice=1", status: http.StatusSeeOther, location: "/shop/train"},
+		{target: "/shop/?back", status: http.StatusSeeOther, location: "/shop/intro"},
+		{target: "/shop/intro", status: http.StatusOK, body: "You have 1 coins and a ticket.|1 Board the train|2 Walk home"},
+		{target: "/shop/?back=5", status: http.StatusSeeOther, location: "/shop/intro"},
+		{target: "/shop/intro", status: http.StatusOK, body: "You have 3 coins.|0 Buy a ticket|2 Walk home"},
+	}
+	for _, tc := range cases {
+		w := get(tc.target)
+		if w.Code != tc.status {
+			t.Fatalf("%s: want status %d, got %d", tc.target, tc.status, w.Code)
+		}
+		if got := w.Header().Get("Location"); got != tc.location {
+			t.Errorf("%s: want location %q, got %q", tc.target, tc.location, got)
+		}
+		if tc.body != "" && w.Body.String() != tc.body {
+			t.Errorf("%s: want body %q, got %q", tc.target, tc.body, w.Body.String())
+		}
+	}
+}
+
+func TestDecodeState(t *testing.T) {
+	st := State{Arc: "intro", Vars: map[string]int{"coins": 3}, Items: []string{"ticket"}}
+	value, err := EncodeState(st, []byte("secret"))
+	if err != nil {
+		t.Fatalf("EncodeState received an error: %s", err.Error())
+	}
+
+	got, err := DecodeState(value, []byte("secret"))
+	if err != nil {
+		t.Fatalf("DecodeState received an error: %s", err.Error())
+	}
+	if got.Arc != st.Arc || got.Vars["coins"] != 3 || !got.Has("ticket") {
+		t.Errorf("DecodeState: want %+v, got %+v", st, got)
+	}
+
+	if _, err := DecodeState(value, []byte("other")); err == nil {
+		t.Errorf("DecodeState with another key: want an error, got none")
+	}
+	payload, signature, _ := strings.Cut(value, ".")
+	if _, err := DecodeState(payload+"x."+signature, []byte("secret")); err == nil {
+		t.Errorf("DecodeState of a changed state: want an error, got none")
+	}
+}
+
+func TestHandlerSave(t *testing.T) {
+	lib := openShop(t)
+	story, _ := lib.Story("shop")
+	tmpl := template.Must(template.New("arc").Parse(`{{.SaveURL}}`))
+	handler := NewHandler(lib, tmpl, tmpl, []byte("secret"))
+	key := storyKey([]byte("secret"), "shop")
+
+	st, err := story.Choose(story.NewState(), 0)
+	if err != nil {
+		t.Fatalf("Choose received an error: %s", err.Error())
+	}
+	value, err := EncodeState(st, key)
+	if err != nil {
+		t.Fatalf("EncodeState received an error: %s", err.Error())
+	}
+	r := httptest.NewRequest(http.MethodGet, "/shop/intro", nil)
+	r.AddCookie(&http.Cookie{Name: stateCookie, Value: value})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	saveURL := w.Body.String()
+	if !strings.HasPrefix(saveURL, "http://example.com/shop/?save=") {
+		t.Fatalf("SaveURL: want a link to example.com/shop/, got %q", saveURL)
+	}
+
+	// a new reader opening the link gets the state
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, saveURL, nil))
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("save: want status %d, got %d", http.StatusSeeOther, w.Code)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Path != "/shop/" {
+		t.Fatalf("save: want the state cookie of /shop/, got %v", cookies)
+	}
+	got, err := DecodeState(cookies[0].Value, key)
+	if err != nil {
+		t.Fatalf("DecodeState received an error: %s", err.Error())
+	}
+	if got.Vars["coins"] != 1 || !got.Has("ticket") || len(got.History) != 1 {
+		t.Errorf("save: want the saved state, got %+v", got)
+	}
+
+	other := NewHandler(lib, tmpl, tmpl, []byte("other"))
+	w = httptest.NewRecorder()
+	other.ServeHTTP(w, httptest.NewRequest(http.MethodGet, saveURL, nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("save of another server: want status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestHandlerReloadedStory(t *testing.T) {
+	lib := openShop(t)
+	tmpl := template.Must(template.New("arc").Parse(`{{range .Story}}{{.}}{{end}}`))
+	handler := NewHandler(lib, tmpl, tmpl, []byte("secret"))
+	story, _ := lib.Story("shop")
+	value, err := EncodeState(story.NewState(), storyKey([]byte("secret"), "shop"))
+	if err != nil {
+		t.Fatalf("EncodeState received an error: %s", err.Error())
+	}
+
+	// the story gets a variable the state of the cookie doesn't have
+	changed := strings.Replace(shopStory, `"variables": {"coins": 3}`, `"variables": {"coins": 3, "gems": 2}`, 1)
+	changed = strings.Replace(changed, `coins{{if`, `coins, {{.gems}} gems{{if`, 1)
+	path := filepath.Join(lib.dir, "shop.json")
+	if err := os.WriteFile(path, []byte(changed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := lib.Reload("shop"); err != nil {
+		t.Fatalf("Reload received an error: %s", err.Error())
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/shop/intro", nil)
+	r.AddCookie(&http.Cookie{Name: stateCookie, Value: value})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Body.String() != "You have 3 coins, 2 gems." {
+		t.Errorf("cookie of the old story: want the page with 2 gems, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shop/?save="+value, nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("save of the old story: want the state cookie, got %v", cookies)
+	}
+	if got, err := DecodeState(cookies[0].Value, storyKey([]byte("secret"), "shop")); err != nil || got.Vars["gems"] != 2 {
+		t.Errorf("save of the old story: want a state with 2 gems, got %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,195 @@
+package cyoa
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// settleTime is how long a story file stays unchanged before the story is
+// reloaded, so a story written in several steps is read once, complete
+const settleTime = 100 * time.Millisecond
+
+// storyExt is the extension of the story files of a Library
+const storyExt = ".json"
+
+// Library is the stories of the JSON files of a directory, by the name of
+// their file without .json. It can reload a story when its file changes, a
+// story that fails to load keeps its previous version
+type Library struct {
+	dir string
+
+	mu      sync.RWMutex // guards stories
+	stories map[string]Story
+
+	watchMu sync.Mutex // guards watcher
+	watcher *fsnotify.Watcher
+}
+
+// Entry is a story of the index of a Library
+type Entry struct {
+	Name  string
+	Title string
+}
+
+// LoadStory decodes the story of the JSON file name
+func LoadStory(name string) (Story, error) {
+	storyFile, err := os.Open(name)
+	if err != nil {
+		return Story{}, err
+	}
+
+	defer storyFile.Close()
+
+	// deserialize json into struct
+	return JSONStory(storyFile)
+}
+
+// OpenLibrary loads the stories of dir, it fails when one of them can't be
+// loaded
+func OpenLibrary(dir string) (*Library, error) {
+	l := &Library{dir: filepath.Clean(dir), stories: map[string]Story{}}
+	files, err := filepath.Glob(filepath.Join(l.dir, "*"+storyExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		story, err := LoadStory(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		l.stories[storyName(file)] = story
+	}
+	return l, nil
+}
+
+func storyName(file string) string {
+	return strings.TrimSuffix(filepath.Base(file), storyExt)
+}
+
+// Story returns the story called name
+func (l *Library) Story(name string) (Story, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	story, ok := l.stories[name]
+	return story, ok
+}
+
+// Index returns the stories sorted by name, with their title or else the
+// title of their first arc
+func (l *Library) Index() []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(l.stories))
+	for name, story := range l.stories {
+		title := story.Title
+		if title == "" {
+			title = story.Arcs[story.FirstArc()].Title
+		}
+		if title == "" {
+			title = name
+		}
+		entries = append(entries, Entry{Name: name, Title: title})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// Reload reads the story called name again, or drops it when its file is
+// gone. On error the previous version stays in use
+func (l *Library) Reload(name string) error {
+	story, err := LoadStory(filepath.Join(l.dir, name+storyExt))
+	if errors.Is(err, fs.ErrNotExist) {
+		l.mu.Lock()
+		delete(l.stories, name)
+		l.mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.stories[name] = story
+	l.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the stories every time their files change until Close is
+// called, the files added to the directory are loaded too
+func (l *Library) Watch() error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := w.Add(l.dir); err != nil {
+		w.Close()
+		return err
+	}
+
+	l.watchMu.Lock()
+	l.watcher = w
+	l.watchMu.Unlock()
+
+	go l.watch(w)
+	return nil
+}
+
+// Close stops watching the stories
+func (l *Library) Close() error {
+	l.watchMu.Lock()
+	defer l.watchMu.Unlock()
+
+	if l.watcher == nil {
+		return nil
+	}
+	err := l.watcher.Close()
+	l.watcher = nil
+	return err
+}
+
+// watch reloads each story settleTime after the last event of its file,
+// the stories changed together are reloaded on their own
+func (l *Library) watch(w *fsnotify.Watcher) {
+	pending := map[string]*time.Timer{}
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if filepath.Ext(event.Name) != storyExt || event.Op == fsnotify.Chmod {
+				continue
+			}
+			name := storyName(event.Name)
+			if t, ok := pending[name]; ok {
+				t.Reset(settleTime)
+				continue
+			}
+			pending[name] = time.AfterFunc(settleTime, func() {
+				if err := l.Reload(name); err != nil {
+					log.Printf("Story %s not reloaded, its previous version stays: %v", name, err)
+					return
+				}
+				log.Printf("Story %s reloaded", name)
+			})
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Failed to watch the stories of %s: %v", l.dir, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package cyoa
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLibrary(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("plain.json", `{"intro": {"title": "Plain Intro", "options": [{"text": "end", "arc": "end"}]}, "end": {"end": true}}`)
+	write("titled.json", `{"title": "Titled", "start": "first", "arcs": {"first": {"title": "First", "end": true}}}`)
+	write("notes.txt", `not a story`)
+
+	lib, err := OpenLibrary(dir)
+	if err != nil {
+		t.Fatalf("OpenLibrary received an error: %s", err.Error())
+	}
+	want := []Entry{{"plain", "Plain Intro"}, {"titled", "Titled"}}
+	if got := lib.Index(); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Index: want %v, got %v", want, got)
+	}
+	if story, _ := lib.Story("titled"); story.NewState().Arc != "first" {
+		t.Errorf("NewState: want the start arc first, got %q", story.NewState().Arc)
+	}
+
+	if err := lib.Watch(); err != nil {
+		t.Fatalf("Watch received an error: %s", err.Error())
+	}
+	defer lib.Close()
+
+	write("titled.json", `{"title": "Retitled", "arcs": {"intro": {"end": true}}}`)
+	write("broken.json", `{`)
+	write("added.json", `{"intro": {"end": true}}`)
+	if err := os.Remove(filepath.Join(dir, "plain.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	want = []Entry{{"added", "added"}, {"titled", "Retitled"}}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := lib.Index()
+		if len(got) == 2 && got[0] == want[0] && got[1] == want[1] {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Index after the changes: want %v, got %v", want, got)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// a story that fails to load keeps its previous version
+	write("titled.json", `{"arcs": `)
+	time.Sleep(5 * settleTime)
+	if story, ok := lib.Story("titled"); !ok || story.Title != "Retitled" {
+		t.Errorf("Story after a broken change: want the previous version, got %+v", story)
+	}
+}
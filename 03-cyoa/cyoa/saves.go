@@ -0,0 +1,75 @@
+package cyoa
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Slot is a named save of the terminal mode
+type Slot struct {
+	Story   string    `json:"story"` // the file of the story the state is in
+	State   State     `json:"state"`
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// LoadSlots reads the save slots of the JSON file name, there are none when
+// it doesn't exist yet
+func LoadSlots(name string) (map[string]Slot, error) {
+	slots := map[string]Slot{}
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return slots, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &slots); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return slots, nil
+}
+
+// SaveSlot writes slot to the JSON file name as slot, replacing the slot of
+// the same name. The file is replaced at once so a failed write doesn't
+// lose the other slots
+func SaveSlot(name, slot string, s Slot) error {
+	slots, err := LoadSlots(name)
+	if err != nil {
+		return err
+	}
+	slots[slot] = s
+
+	data, err := json.MarshalIndent(slots, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// SlotNames returns the names of slots sorted
+func SlotNames(slots map[string]Slot) []string {
+	names := make([]string, 0, len(slots))
+	for name := range slots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,51 @@
+package cyoa
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveSlot(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "saves.json")
+
+	slots, err := LoadSlots(name)
+	if err != nil {
+		t.Fatalf("LoadSlots of a missing file received an error: %s", err.Error())
+	}
+	if len(slots) != 0 {
+		t.Errorf("LoadSlots of a missing file: want no slots, got %v", slots)
+	}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	first := Slot{Story: "gopher.json", State: State{Arc: "intro"}, SavedAt: now}
+	second := Slot{Story: "gopher.json", State: State{Arc: "denver", Vars: map[string]int{"coins": 2}}, SavedAt: now}
+	for _, save := range []struct {
+		slot string
+		s    Slot
+	}{{"a", first}, {"b", first}, {"b", second}} {
+		if err := SaveSlot(name, save.slot, save.s); err != nil {
+			t.Fatalf("SaveSlot received an error: %s", err.Error())
+		}
+	}
+
+	slots, err = LoadSlots(name)
+	if err != nil {
+		t.Fatalf("LoadSlots received an error: %s", err.Error())
+	}
+	if got := SlotNames(slots); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("SlotNames: want [a b], got %v", got)
+	}
+	if got := slots["b"]; got.State.Arc != "denver" || got.State.Vars["coins"] != 2 || !got.SavedAt.Equal(now) {
+		t.Errorf("slot b: want %+v, got %+v", second, got)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("SaveSlot left temporary files: %v", entries)
+	}
+}
@@ -0,0 +1,348 @@
+package cyoa
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+var (
+	// ErrUnknownArc is returned when the state of a reader is on an arc
+	// the story doesn't have
+	ErrUnknownArc = errors.New("cyoa: unknown arc")
+	// ErrInvalidChoice is returned for an option the reader can't pick
+	ErrInvalidChoice = errors.New("cyoa: invalid choice")
+)
+
+// nameRe is the form of the names of the variables and items, so the
+// templates can use them like {{.coins}}
+var nameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// State is where a reader is in a story, with the values of the variables
+// and the items carried
+type State struct {
+	Arc   string         `json:"arc"`
+	Vars  map[string]int `json:"vars,omitempty"`
+	Items []string       `json:"items,omitempty"` // sorted
+	// History are the states before this one, oldest first and without
+	// their own history, for Back. The oldest are dropped when the state
+	// gets too big for a cookie, see maxStateSize
+	History []State `json:"history,omitempty"`
+}
+
+// NewState returns the state of a reader starting the story
+func (s Story) NewState() State {
+	st := State{Arc: s.FirstArc(), Vars: map[string]int{}}
+	for name, value := range s.Variables {
+		st.Vars[name] = value
+	}
+	return st
+}
+
+// Resume returns st, a state kept from an earlier version of the story,
+// with the variables it lacks set to their start value in s, so the
+// paragraphs using a variable added since still render. Its history gets
+// them too, for Back
+func (s Story) Resume(st State) State {
+	st.Vars = s.fillVars(st.Vars)
+	history := make([]State, len(st.History))
+	for i, prev := range st.History {
+		prev.Vars = s.fillVars(prev.Vars)
+		history[i] = prev
+	}
+	if len(history) > 0 {
+		st.History = history
+	}
+	return st
+}
+
+// fillVars returns a copy of vars with the variables of s it lacks
+func (s Story) fillVars(vars map[string]int) map[string]int {
+	filled := make(map[string]int, len(s.Variables))
+	for name, value := range s.Variables {
+		filled[name] = value
+	}
+	for name, value := range vars {
+		filled[name] = value
+	}
+	return filled
+}
+
+// Has reports whether the reader carries item
+func (st State) Has(item string) bool {
+	i := sort.SearchStrings(st.Items, item)
+	return i < len(st.Items) && st.Items[i] == item
+}
+
+// clone returns a copy of st without its history, sharing nothing with it
+func (st State) clone() State {
+	c := State{Arc: st.Arc, Vars: make(map[string]int, len(st.Vars))}
+	for name, value := range st.Vars {
+		c.Vars[name] = value
+	}
+	c.Items = append([]string(nil), st.Items...)
+	return c
+}
+
+// Back returns the state of the reader n choices ago, or the oldest one
+// kept when there are fewer
+func (st State) Back(n int) State {
+	if n <= 0 || len(st.History) == 0 {
+		return st
+	}
+	if n > len(st.History) {
+		n = len(st.History)
+	}
+	i := len(st.History) - n
+	prev := st.History[i]
+	prev.History = st.History[:i:i]
+	return prev
+}
+
+// condition is a parsed Option.Requires
+type condition struct {
+	item   string // tested by has, or else name op value is
+	negate bool
+	name   string
+	op     string
+	value  int
+}
+
+func parseCondition(s string) (condition, error) {
+	fields := strings.Fields(s)
+	switch {
+	case len(fields) == 2 && (fields[0] == "has" || fields[0] == "!has"):
+		if !nameRe.MatchString(fields[1]) {
+			return condition{}, fmt.Errorf("invalid item %q in condition %q", fields[1], s)
+		}
+		return condition{item: fields[1], negate: fields[0] == "!has"}, nil
+	case len(fields) == 3:
+		c := condition{name: fields[0], op: fields[1]}
+		switch c.op {
+		case "==", "!=", "<", "<=", ">", ">=":
+		default:
+			return c, fmt.Errorf("invalid comparison %q in condition %q", c.op, s)
+		}
+		if !nameRe.MatchString(c.name) {
+			return c, fmt.Errorf("invalid variable %q in condition %q", c.name, s)
+		}
+		value, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return c, fmt.Errorf("invalid number %q in condition %q", fields[2], s)
+		}
+		c.value = value
+		return c, nil
+	}
+	return condition{}, fmt.Errorf("invalid condition %q, expected like \"has ticket\" or \"coins >= 5\"", s)
+}
+
+func (c condition) test(st State) bool {
+	if c.item != "" {
+		return st.Has(c.item) != c.negate
+	}
+	v := st.Vars[c.name]
+	switch c.op {
+	case "==":
+		return v == c.value
+	case "!=":
+		return v != c.value
+	case "<":
+		return v < c.value
+	case "<=":
+		return v <= c.value
+	case ">":
+		return v > c.value
+	}
+	return v >= c.value
+}
+
+// effect is a parsed Option.Sets
+type effect struct {
+	item   string // added or removed, or else name op value is set
+	remove bool
+	name   string
+	op     string
+	value  int
+}
+
+func parseEffect(s string) (effect, error) {
+	fields := strings.Fields(s)
+	switch {
+	case len(fields) == 2 && (fields[0] == "add" || fields[0] == "remove"):
+		if !nameRe.MatchString(fields[1]) {
+			return effect{}, fmt.Errorf("invalid item %q in %q", fields[1], s)
+		}
+		return effect{item: fields[1], remove: fields[0] == "remove"}, nil
+	case len(fields) == 3:
+		e := effect{name: fields[0], op: fields[1]}
+		switch e.op {
+		case "=", "+=", "-=":
+		default:
+			return e, fmt.Errorf("invalid operator %q in %q", e.op, s)
+		}
+		if !nameRe.MatchString(e.name) {
+			return e, fmt.Errorf("invalid variable %q in %q", e.name, s)
+		}
+		value, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return e, fmt.Errorf("invalid number %q in %q", fields[2], s)
+		}
+		e.value = value
+		return e, nil
+	}
+	return effect{}, fmt.Errorf("invalid change %q, expected like \"add ticket\" or \"coins -= 5\"", s)
+}
+
+func (e effect) apply(st *State) {
+	switch {
+	case e.item != "" && e.remove:
+		if i := sort.SearchStrings(st.Items, e.item); i < len(st.Items) && st.Items[i] == e.item {
+			st.Items = append(st.Items[:i], st.Items[i+1:]...)
+		}
+	case e.item != "":
+		if !st.Has(e.item) {
+			st.Items = append(st.Items, e.item)
+			sort.Strings(st.Items)
+		}
+	case e.op == "=":
+		st.Vars[e.name] = e.value
+	case e.op == "+=":
+		st.Vars[e.name] += e.value
+	case e.op == "-=":
+		st.Vars[e.name] -= e.value
+	}
+}
+
+// Available reports whether st meets the requirements of o, an option with
+// an invalid condition is never available, see Validate
+func (o Option) Available(st State) bool {
+	for _, s := range o.Requires {
+		c, err := parseCondition(s)
+		if err != nil || !c.test(st) {
+			return false
+		}
+	}
+	return true
+}
+
+// Choose returns the state of the reader after picking the option i of the
+// arc of st, st itself is left unchanged
+func (s Story) Choose(st State, i int) (State, error) {
+	arc, ok := s.Arcs[st.Arc]
+	if !ok {
+		return st, fmt.Errorf("%w %q", ErrUnknownArc, st.Arc)
+	}
+	if i < 0 || i >= len(arc.Options) || !arc.Options[i].Available(st) {
+		return st, fmt.Errorf("%w %d on arc %q", ErrInvalidChoice, i, st.Arc)
+	}
+	o := arc.Options[i]
+	if _, ok := s.Arcs[o.Arc]; !ok {
+		return st, fmt.Errorf("%w %q", ErrUnknownArc, o.Arc)
+	}
+
+	next := st.clone()
+	next.History = append(append(make([]State, 0, len(st.History)+1), st.History...), st.clone())
+	for _, set := range o.Sets {
+		e, err := parseEffect(set)
+		if err != nil {
+			return st, err
+		}
+		e.apply(&next)
+	}
+	next.Arc = o.Arc
+	return fitHistory(next), nil
+}
+
+// Page is an arc as a reader sees it, the arc.html and arc.txt templates
+// render it
+type Page struct {
+	Arc     string // the name of the arc
+	Title   string
+	Story   []string // the paragraphs rendered for the state
+	Options []Choice // the options available to the reader
+	Vars    map[string]int
+	Items   []string
+	Trail   []Crumb // the arcs of the history of the reader, then this one
+	// Base is the path of the story and SaveURL a link restoring the state
+	// of the reader, both set by NewHandler
+	Base    string
+	SaveURL string
+}
+
+// Crumb is an arc of the Trail of a Page
+type Crumb struct {
+	Arc   string
+	Title string
+	Back  int // the number of choices to go back to it, 0 for the current arc
+}
+
+// Choice is an available option of a Page
+type Choice struct {
+	Index int // the index of the option in the arc, for Choose
+	Text  string
+	Arc   string
+}
+
+// Page returns the arc of st as the reader sees it
+func (s Story) Page(st State) (Page, error) {
+	arc, ok := s.Arcs[st.Arc]
+	if !ok {
+		return Page{}, fmt.Errorf("%w %q", ErrUnknownArc, st.Arc)
+	}
+
+	page := Page{Arc: st.Arc, Title: arc.Title, Vars: st.Vars, Items: st.Items}
+	for i, prev := range st.History {
+		page.Trail = append(page.Trail, Crumb{Arc: prev.Arc, Title: s.Arcs[prev.Arc].Title, Back: len(st.History) - i})
+	}
+	page.Trail = append(page.Trail, Crumb{Arc: st.Arc, Title: arc.Title})
+	for _, text := range arc.Story {
+		p, err := render(text, st)
+		if err != nil {
+			return page, fmt.Errorf("arc %s: %v", st.Arc, err)
+		}
+		page.Story = append(page.Story, p)
+	}
+	for i, o := range arc.Options {
+		if !o.Available(st) {
+			continue
+		}
+		text, err := render(o.Text, st)
+		if err != nil {
+			return page, fmt.Errorf("arc %s: %v", st.Arc, err)
+		}
+		page.Options = append(page.Options, Choice{Index: i, Text: text, Arc: o.Arc})
+	}
+	return page, nil
+}
+
+// render executes text as a template of st, the text without actions is
+// returned as is
+func render(text string, st State) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	t, err := parseText(text)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	t = t.Funcs(template.FuncMap{"has": st.Has})
+	if err := t.Execute(&b, st.Vars); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// parseText parses the template of a paragraph or option text, an unknown
+// variable is an error instead of printing <no value>
+func parseText(text string) (*template.Template, error) {
+	return template.New("text").
+		Funcs(template.FuncMap{"has": func(string) bool { return false }}).
+		Option("missingkey=error").
+		Parse(text)
+}
@@ -0,0 +1,245 @@
+package cyoa
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const shopStory = `{
+	"variables": {"coins": 3},
+	"items": ["ticket", "map"],
+	"arcs": {
+		"intro": {
+			"story": ["You have {{.coins}} coins{{if has \"ticket\"}} and a ticket{{end}}."],
+			"options": [
+				{"text": "Buy a ticket", "arc": "intro", "requires": ["!has ticket", "coins >= 2"], "sets": ["add ticket", "coins -= 2"]},
+				{"text": "Board the train", "arc": "train", "requires": ["has ticket"], "sets": ["remove ticket"]},
+				{"text": "Walk home", "arc": "home"}
+			]
+		},
+		"train": {"end": true},
+		"home": {"end": true}
+	}
+}`
+
+func loadShop(t *testing.T) Story {
+	t.Helper()
+	story, err := JSONStory(strings.NewReader(shopStory))
+	if err != nil {
+		t.Fatalf("JSONStory received an error: %s", err.Error())
+	}
+	return story
+}
+
+func TestPage(t *testing.T) {
+	story := loadShop(t)
+
+	st := story.NewState()
+	page, err := story.Page(st)
+	if err != nil {
+		t.Fatalf("Page received an error: %s", err.Error())
+	}
+	if want := []string{"You have 3 coins."}; !reflect.DeepEqual(page.Story, want) {
+		t.Errorf("Page story: want %q, got %q", want, page.Story)
+	}
+	if want := []Choice{{0, "Buy a ticket", "intro"}, {2, "Walk home", "home"}}; !reflect.DeepEqual(page.Options, want) {
+		t.Errorf("Page options: want %v, got %v", want, page.Options)
+	}
+
+	st, err = story.Choose(st, 0)
+	if err != nil {
+		t.Fatalf("Choose received an error: %s", err.Error())
+	}
+	page, err = story.Page(st)
+	if err != nil {
+		t.Fatalf("Page received an error: %s", err.Error())
+	}
+	if want := []string{"You have 1 coins and a ticket."}; !reflect.DeepEqual(page.Story, want) {
+		t.Errorf("Page story: want %q, got %q", want, page.Story)
+	}
+	if want := []Choice{{1, "Board the train", "train"}, {2, "Walk home", "home"}}; !reflect.DeepEqual(page.Options, want) {
+		t.Errorf("Page options: want %v, got %v", want, page.Options)
+	}
+}
+
+func TestChoose(t *testing.T) {
+	story := loadShop(t)
+	start := story.NewState()
+
+	bought, err := story.Choose(start, 0)
+	if err != nil {
+		t.Fatalf("Choose received an error: %s", err.Error())
+	}
+	if bought.Arc != "intro" || bought.Vars["coins"] != 1 || !bought.Has("ticket") {
+		t.Errorf("Choose buy: want intro with 1 coin and a ticket, got %+v", bought)
+	}
+	if start.Vars["coins"] != 3 || start.Has("ticket") {
+		t.Errorf("Choose changed the state it was given: %+v", start)
+	}
+
+	if _, err := story.Choose(bought, 0); !errors.Is(err, ErrInvalidChoice) {
+		t.Errorf("Choose buy twice: want ErrInvalidChoice, got %v", err)
+	}
+	if _, err := story.Choose(start, 1); !errors.Is(err, ErrInvalidChoice) {
+		t.Errorf("Choose board without a ticket: want ErrInvalidChoice, got %v", err)
+	}
+	if _, err := story.Choose(start, 3); !errors.Is(err, ErrInvalidChoice) {
+		t.Errorf("Choose out of range: want ErrInvalidChoice, got %v", err)
+	}
+
+	boarded, err := story.Choose(bought, 1)
+	if err != nil {
+		t.Fatalf("Choose received an error: %s", err.Error())
+	}
+	if boarded.Arc != "train" || boarded.Has("ticket") {
+		t.Errorf("Choose board: want train without the ticket, got %+v", boarded)
+	}
+	if _, err := story.Choose(State{Arc: "nowhere"}, 0); !errors.Is(err, ErrUnknownArc) {
+		t.Errorf("Choose unknown arc: want ErrUnknownArc, got %v", err)
+	}
+}
+
+func TestConditions(t *testing.T) {
+	st := State{Vars: map[string]int{"coins": 3}, Items: []string{"map"}}
+	cases := []struct {
+		condition string
+		want      bool
+		invalid   bool
+	}{
+		{condition: "has map", want: true},
+		{condition: "!has map", want: false},
+		{condition: "has ticket", want: false},
+		{condition: "coins == 3", want: true},
+		{condition: "coins != 3", want: false},
+		{condition: "coins < 3", want: false},
+		{condition: "coins <= 3", want: true},
+		{condition: "coins > 2", want: true},
+		{condition: "coins >= 4", want: false},
+		{condition: "lives == 0", want: true},
+		{condition: "coins => 3", invalid: true},
+		{condition: "coins >= three", invalid: true},
+		{condition: "has", invalid: true},
+	}
+	for _, tc := range cases {
+		c, err := parseCondition(tc.condition)
+		if tc.invalid {
+			if err == nil {
+				t.Errorf("parseCondition(%q): want an error, got none", tc.condition)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseCondition(%q) received an error: %s", tc.condition, err.Error())
+			continue
+		}
+		if got := c.test(st); got != tc.want {
+			t.Errorf("%q: want %v, got %v", tc.condition, tc.want, got)
+		}
+	}
+}
+
+func TestBack(t *testing.T) {
+	story := loadShop(t)
+	start := story.NewState()
+	bought, _ := story.Choose(start, 0)
+	boarded, err := story.Choose(bought, 1)
+	if err != nil {
+		t.Fatalf("Choose received an error: %s", err.Error())
+	}
+
+	page, err := story.Page(boarded)
+	if err != nil {
+		t.Fatalf("Page received an error: %s", err.Error())
+	}
+	var trail []string
+	for _, c := range page.Trail {
+		trail = append(trail, fmt.Sprintf("%s/%d", c.Arc, c.Back))
+	}
+	if want := "intro/2 intro/1 train/0"; strings.Join(trail, " ") != want {
+		t.Errorf("Page trail: want %s, got %s", want, strings.Join(trail, " "))
+	}
+
+	back := boarded.Back(1)
+	if back.Arc != "intro" || back.Vars["coins"] != 1 || !back.Has("ticket") || len(back.History) != 1 {
+		t.Errorf("Back 1: want intro with 1 coin, a ticket and 1 state of history, got %+v", back)
+	}
+	back = boarded.Back(5)
+	if back.Arc != "intro" || back.Vars["coins"] != 3 || back.Has("ticket") || len(back.History) != 0 {
+		t.Errorf("Back 5: want the start, got %+v", back)
+	}
+	if back := start.Back(1); back.Arc != "intro" || len(back.History) != 0 {
+		t.Errorf("Back at the start: want the start, got %+v", back)
+	}
+
+	// a new choice after going back forgets the states gone back from
+	home, err := story.Choose(boarded.Back(1), 2)
+	if err != nil {
+		t.Fatalf("Choose received an error: %s", err.Error())
+	}
+	if len(home.History) != 2 || home.History[1].Vars["coins"] != 1 {
+		t.Errorf("Choose after Back: want the 2 states to intro in the history, got %+v", home.History)
+	}
+
+	// the history stays small enough for a cookie
+	loop, err := JSONStory(strings.NewReader(`{
+		"variables": {"coins": 0, "lives": 3, "score": 0},
+		"items": ["ticket", "sandwich"],
+		"arcs": {"platform": {"options": [{"text": "again", "arc": "platform", "sets": ["add ticket", "add sandwich", "coins += 1000", "score += 100000"]}]}},
+		"start": "platform"
+	}`))
+	if err != nil {
+		t.Fatalf("JSONStory received an error: %s", err.Error())
+	}
+	st := loop.NewState()
+	for i := 0; i < 200; i++ {
+		if st, err = loop.Choose(st, 0); err != nil {
+			t.Fatalf("Choose received an error: %s", err.Error())
+		}
+		value, err := EncodeState(st, []byte("secret"))
+		if err != nil {
+			t.Fatalf("EncodeState received an error: %s", err.Error())
+		}
+		if len(value) > maxStateSize || len(value) >= 4096 {
+			t.Fatalf("EncodeState after %d choices: want at most %d bytes, got %d", i+1, maxStateSize, len(value))
+		}
+	}
+	if len(st.History) < 20 || len(st.History) >= 200 {
+		t.Errorf("History: want the oldest states dropped but at least 20 kept, got %d", len(st.History))
+	}
+	if back := st.Back(len(st.History)); back.Vars["coins"] != 1000*(200-len(st.History)) {
+		t.Errorf("Back to the oldest state: want %d coins, got %d", 1000*(200-len(st.History)), back.Vars["coins"])
+	}
+}
+
+func TestResume(t *testing.T) {
+	story := loadShop(t)
+	st, err := story.Choose(story.NewState(), 0)
+	if err != nil {
+		t.Fatalf("Choose received an error: %s", err.Error())
+	}
+
+	// the story gets a new variable used by a paragraph
+	story.Variables["gems"] = 2
+	story.Arcs["intro"].Story[0] += " And {{.gems}} gems."
+	if _, err := story.Page(st); err == nil {
+		t.Fatalf("Page of the old state: want an error for gems, got none")
+	}
+
+	resumed := story.Resume(st)
+	page, err := story.Page(resumed)
+	if err != nil {
+		t.Fatalf("Page of the resumed state received an error: %s", err.Error())
+	}
+	if want := "You have 1 coins and a ticket. And 2 gems."; page.Story[0] != want {
+		t.Errorf("Page: want %q, got %q", want, page.Story[0])
+	}
+	if back := resumed.Back(1); back.Vars["gems"] != 2 || back.Vars["coins"] != 3 {
+		t.Errorf("Back of the resumed state: want 2 gems and 3 coins, got %v", back.Vars)
+	}
+	if _, ok := st.Vars["gems"]; ok {
+		t.Errorf("Resume: want st untouched, got %v", st.Vars)
+	}
+}
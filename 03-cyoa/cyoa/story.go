@@ -0,0 +1,111 @@
+package cyoa
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// StartArc is the arc a story starts with when it doesn't set Start
+const StartArc = "intro"
+
+// Story is a story read by JSONStory. It is either a JSON object of the arcs
+// by name, or a JSON object declaring the state of the reader too:
+//
+//	{
+//	  "title": "The Station",
+//	  "start": "platform",
+//	  "variables": {"coins": 3},
+//	  "items": ["ticket"],
+//	  "arcs": {"platform": {...}}
+//	}
+type Story struct {
+	// Title is shown in the index of a Library, the title of the first
+	// arc is used without it
+	Title string `json:"title,omitempty"`
+	// Start is the arc the story starts with, StartArc without it
+	Start string `json:"start,omitempty"`
+	// Variables are the numbers kept for the reader with their starting
+	// value, the options test and change them, see Option
+	Variables map[string]int `json:"variables,omitempty"`
+	// Items are the names of the items the reader can carry, the reader
+	// starts without any
+	Items []string       `json:"items,omitempty"`
+	Arcs  map[string]Arc `json:"arcs"`
+}
+
+// storyFields are the keys of the JSON object of a Story
+var storyFields = map[string]bool{"title": true, "start": true, "variables": true, "items": true, "arcs": true}
+
+// FirstArc returns the arc the story starts with
+func (s Story) FirstArc() string {
+	if s.Start != "" {
+		return s.Start
+	}
+	return StartArc
+}
+
+// Arc is a chapter of a story, the reader picks one of its options to go
+// to the next arc. The paragraphs of Story are text/template templates of
+// the state of the reader, like "You have {{.coins}} coins" or
+// "{{if has "ticket"}}...{{end}}"
+type Arc struct {
+	Title   string   `json:"title"`
+	Story   []string `json:"story"`
+	Options []Option `json:"options"`
+	// End marks an arc without options as an ending of the story, the
+	// other arcs without options are dead ends reported by Validate
+	End bool `json:"end,omitempty"`
+}
+
+// Option is a choice of an arc leading to another arc. Requires are the
+// conditions the state of the reader must meet for the option to be shown:
+//
+//	has ticket    !has ticket    coins >= 5
+//
+// with the comparisons ==, !=, <, <=, > and >=. Sets are the changes made
+// to the state when the option is picked:
+//
+//	add ticket    remove ticket    coins = 1    coins += 1    coins -= 5
+type Option struct {
+	Text     string   `json:"text"`
+	Arc      string   `json:"arc"`
+	Requires []string `json:"requires,omitempty"`
+	Sets     []string `json:"sets,omitempty"`
+}
+
+// JSONStory decodes a story from r
+func JSONStory(r io.Reader) (Story, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Story{}, err
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return Story{}, err
+	}
+	if _, ok := keys["arcs"]; ok && declaresState(keys) {
+		var story Story
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err := dec.Decode(&story)
+		return story, err
+	}
+
+	// the object is the arcs
+	var story Story
+	err = json.Unmarshal(data, &story.Arcs)
+	return story, err
+}
+
+// declaresState reports whether the keys of a story are the fields of
+// Story rather than arc names
+func declaresState(keys map[string]json.RawMessage) bool {
+	for key := range keys {
+		if !storyFields[key] {
+			return false
+		}
+	}
+	return true
+}
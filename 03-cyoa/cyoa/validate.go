@@ -0,0 +1,273 @@
+package cyoa
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Problem is an issue found in a story by Validate
+type Problem struct {
+	Arc     string // empty when the problem is about the whole story
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Arc == "" {
+		return p.Message
+	}
+	return fmt.Sprintf("arc %s: %s", p.Arc, p.Message)
+}
+
+// Validate checks the graph of the arcs of story and returns its problems
+// sorted by arc: a missing start arc, options going to arcs that don't
+// exist, arcs the reader can't get to, dead ends not marked as an ending
+// and cycles the reader can't leave to get to an ending. It checks the
+// state too: invalid requires and sets, variables and items the story
+// doesn't declare and text templates that fail
+func Validate(story Story) []Problem {
+	var problems []Problem
+	addf := func(arc, format string, a ...any) {
+		problems = append(problems, Problem{Arc: arc, Message: fmt.Sprintf(format, a...)})
+	}
+
+	for name := range story.Variables {
+		if !nameRe.MatchString(name) {
+			addf("", "invalid variable name %q", name)
+		}
+	}
+	items := map[string]bool{}
+	for _, item := range story.Items {
+		if !nameRe.MatchString(item) {
+			addf("", "invalid item name %q", item)
+		}
+		items[item] = true
+	}
+
+	names := make([]string, 0, len(story.Arcs))
+	for name := range story.Arcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		arc := story.Arcs[name]
+		for i, text := range arc.Story {
+			if err := checkText(story, items, text); err != nil {
+				addf(name, "paragraph %d: %v", i, err)
+			}
+		}
+		for i, o := range arc.Options {
+			if _, ok := story.Arcs[o.Arc]; !ok {
+				addf(name, "option %d %q goes to unknown arc %q", i, o.Text, o.Arc)
+			}
+			if err := checkText(story, items, o.Text); err != nil {
+				addf(name, "option %d: %v", i, err)
+			}
+			for _, s := range o.Requires {
+				c, err := parseCondition(s)
+				if err != nil {
+					addf(name, "option %d: %v", i, err)
+				} else if msg := undeclared(story, items, c.item, c.name); msg != "" {
+					addf(name, "option %d requires %s", i, msg)
+				}
+			}
+			for _, s := range o.Sets {
+				e, err := parseEffect(s)
+				if err != nil {
+					addf(name, "option %d: %v", i, err)
+				} else if msg := undeclared(story, items, e.item, e.name); msg != "" {
+					addf(name, "option %d sets %s", i, msg)
+				}
+			}
+		}
+		switch {
+		case len(arc.Options) == 0 && !arc.End:
+			addf(name, `dead end, mark it "end": true if the story ends here`)
+		case len(arc.Options) > 0 && arc.End:
+			addf(name, "marked as an ending but has %d option(s)", len(arc.Options))
+		}
+	}
+
+	if _, ok := story.Arcs[story.FirstArc()]; !ok {
+		addf("", "missing start arc %q", story.FirstArc())
+	} else {
+		reached := reachable(story, story.FirstArc())
+		for _, name := range names {
+			if !reached[name] {
+				addf(name, "unreachable from %s", story.FirstArc())
+			}
+		}
+	}
+
+	for _, cycle := range trapCycles(story) {
+		addf(cycle[0], "cycle with no exit through %s", strings.Join(cycle, ", "))
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Arc < problems[j].Arc
+	})
+	return problems
+}
+
+// undeclared describes the item or variable name of a condition or change
+// the story doesn't declare, or returns an empty string
+func undeclared(story Story, items map[string]bool, item, name string) string {
+	if item != "" && !items[item] {
+		return fmt.Sprintf("unknown item %q", item)
+	}
+	if _, ok := story.Variables[name]; item == "" && !ok {
+		return fmt.Sprintf("unknown variable %q", name)
+	}
+	return ""
+}
+
+// checkText parses text as a template and executes it with the starting
+// state, catching the unknown variables and items it uses
+func checkText(story Story, items map[string]bool, text string) error {
+	if !strings.Contains(text, "{{") {
+		return nil
+	}
+	t, err := parseText(text)
+	if err != nil {
+		return err
+	}
+	t = t.Funcs(template.FuncMap{"has": func(item string) (bool, error) {
+		if !items[item] {
+			return false, fmt.Errorf("unknown item %q", item)
+		}
+		return false, nil
+	}})
+	return t.Execute(io.Discard, story.NewState().Vars)
+}
+
+// reachable returns the arcs the reader can get to from start
+func reachable(story Story, start string) map[string]bool {
+	reached := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, o := range story.Arcs[name].Options {
+			if _, ok := story.Arcs[o.Arc]; ok && !reached[o.Arc] {
+				reached[o.Arc] = true
+				queue = append(queue, o.Arc)
+			}
+		}
+	}
+	return reached
+}
+
+// trapCycles returns the cycles of arcs from which no ending can be
+// reached, each one sorted from its first arc by name. The arcs without
+// options count as endings, the dead ends are reported on their own
+func trapCycles(story Story) [][]string {
+	// walk the options backwards from the endings
+	from := map[string][]string{}
+	var queue []string
+	exits := map[string]bool{}
+	for name, arc := range story.Arcs {
+		for _, o := range arc.Options {
+			from[o.Arc] = append(from[o.Arc], name)
+		}
+		if len(arc.Options) == 0 {
+			exits[name] = true
+			queue = append(queue, name)
+		}
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, prev := range from[name] {
+			if !exits[prev] {
+				exits[prev] = true
+				queue = append(queue, prev)
+			}
+		}
+	}
+
+	// the cycles are the strongly connected components of the other arcs
+	var cycles [][]string
+	for _, component := range components(story, exits) {
+		if len(component) == 1 && !loops(story.Arcs[component[0]], component[0]) {
+			continue
+		}
+		sort.Strings(component)
+		cycles = append(cycles, component)
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return cycles
+}
+
+func loops(arc Arc, name string) bool {
+	for _, o := range arc.Options {
+		if o.Arc == name {
+			return true
+		}
+	}
+	return false
+}
+
+// components returns the strongly connected components of the arcs not in
+// skip, with Tarjan's algorithm
+func components(story Story, skip map[string]bool) [][]string {
+	var (
+		index   = map[string]int{}
+		low     = map[string]int{}
+		onStack = map[string]bool{}
+		stack   []string
+		next    int
+		result  [][]string
+		connect func(name string)
+	)
+	connect = func(name string) {
+		index[name], low[name] = next, next
+		next++
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, o := range story.Arcs[name].Options {
+			if _, ok := story.Arcs[o.Arc]; !ok || skip[o.Arc] {
+				continue
+			}
+			if _, seen := index[o.Arc]; !seen {
+				connect(o.Arc)
+				if low[o.Arc] < low[name] {
+					low[name] = low[o.Arc]
+				}
+			} else if onStack[o.Arc] && index[o.Arc] < low[name] {
+				low[name] = index[o.Arc]
+			}
+		}
+
+		if low[name] == index[name] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == name {
+					break
+				}
+			}
+			result = append(result, component)
+		}
+	}
+
+	names := make([]string, 0, len(story.Arcs))
+	for name := range story.Arcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, seen := index[name]; !seen && !skip[name] {
+			connect(name)
+		}
+	}
+	return result
+}
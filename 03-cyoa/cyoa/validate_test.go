@@ -0,0 +1,99 @@
+package cyoa
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		problems []string
+	}{
+		{
+			name: "valid",
+			data: `{
+				"intro": {"options": [{"text": "go", "arc": "loop"}, {"text": "stop", "arc": "home"}]},
+				"loop": {"options": [{"text": "again", "arc": "intro"}]},
+				"home": {"end": true}
+			}`,
+		},
+		{
+			name: "broken",
+			data: `{
+				"intro": {"options": [{"text": "go", "arc": "nowhere"}, {"text": "stuck", "arc": "a"}, {"text": "stop", "arc": "cliff"}]},
+				"a": {"options": [{"text": "b", "arc": "b"}]},
+				"b": {"options": [{"text": "a", "arc": "a"}, {"text": "self", "arc": "b"}]},
+				"cliff": {},
+				"lost": {"end": true},
+				"odd": {"end": true, "options": [{"text": "back", "arc": "intro"}]}
+			}`,
+			problems: []string{
+				"arc a: cycle with no exit through a, b",
+				"arc cliff: dead end, mark it \"end\": true if the story ends here",
+				"arc intro: option 0 \"go\" goes to unknown arc \"nowhere\"",
+				"arc lost: unreachable from intro",
+				"arc odd: marked as an ending but has 1 option(s)",
+				"arc odd: unreachable from intro",
+			},
+		},
+		{
+			name: "state",
+			data: `{
+				"variables": {"coins": 1},
+				"items": ["key"],
+				"arcs": {
+					"intro": {
+						"story": ["{{.coins}} coins", "{{.lives}} lives", "{{if has \"lamp\"}}lit{{end}}", "{{if}}"],
+						"options": [
+							{"text": "open", "arc": "home", "requires": ["has key", "coins >= 1", "has lamp", "lives > 0", "coins => 1"]},
+							{"text": "pay", "arc": "home", "sets": ["coins -= 1", "add lamp", "lives = 2", "coins *= 2"]}
+						]
+					},
+					"home": {"end": true}
+				}
+			}`,
+			problems: []string{
+				`arc intro: paragraph 1: template: text:1:2: executing "text" at <.lives>: map has no entry for key "lives"`,
+				`arc intro: paragraph 2: template: text:1:5: executing "text" at <has "lamp">: error calling has: unknown item "lamp"`,
+				`arc intro: paragraph 3: template: text:1: missing value for if`,
+				`arc intro: option 0 requires unknown item "lamp"`,
+				`arc intro: option 0 requires unknown variable "lives"`,
+				`arc intro: option 0: invalid comparison "=>" in condition "coins => 1"`,
+				`arc intro: option 1 sets unknown item "lamp"`,
+				`arc intro: option 1 sets unknown variable "lives"`,
+				`arc intro: option 1: invalid operator "*=" in "coins *= 2"`,
+			},
+		},
+		{
+			name: "no start",
+			data: `{"home": {"end": true}}`,
+			problems: []string{
+				"missing start arc \"intro\"",
+			},
+		},
+		{
+			name: "start",
+			data: `{"start": "begin", "arcs": {"begin": {"options": [{"text": "on", "arc": "home"}]}, "intro": {"end": true}, "home": {"end": true}}}`,
+			problems: []string{
+				"arc intro: unreachable from begin",
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			story, err := JSONStory(strings.NewReader(tc.data))
+			if err != nil {
+				t.Fatalf("JSONStory received an error: %s", err.Error())
+			}
+			var got []string
+			for _, p := range Validate(story) {
+				got = append(got, p.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.problems, "\n") {
+				t.Errorf("Validate:\nwant %s\ngot  %s", strings.Join(tc.problems, "\n     "), strings.Join(got, "\n     "))
+			}
+		})
+	}
+}
@@ -1,121 +1,198 @@
 package main
 
 import (
-	"encoding/json"
+	"bufio"
+	"crypto/rand"
 	"flag"
 	"fmt"
 	"html/template"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aboelkassem/gophercises/cyoa/cyoa"
 )
 
-// generated by https://mholt.github.io/json-to-go
-// Will used in template parsing
-type Story map[string]struct {
-	Title   string   `json:"title"`
-	Story   []string `json:"story"`
-	Options []struct {
-		Text string `json:"text"`
-		Arc  string `json:"arc"`
-	} `json:"options"`
-}
+/*
+$ cyoa [flags]
+	read the -story in the terminal, or serve the -stories directory with
+	-http at /{story}/{arc}. In the terminal type the number of an option,
+	back, save NAME, load NAME or saves
 
-type StoryHandler struct {
-	story Story
-}
+$ cyoa validate [files...]
+	check the arcs of stories and report their problems
+*/
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		runValidate(os.Args[2:])
+		return
+	}
+
 	var (
-		flagStoryJSONFilename = flag.String("story", "gopher.json", "The path to the JSON of strong to be rendered")
-		flagHttp              = flag.Bool("http", false, "Run as a web server")
+		flagStoryJSONFilename = flag.String("story", filepath.Join("stories", "gopher.json"), "The path to the JSON of strong to be rendered in the terminal")
+		flagHttp              = flag.Bool("http", false, "Run as a web server of the stories of -stories")
+		flagStories           = flag.String("stories", "stories", "The directory of the JSON stories of -http, reloaded when they change")
+		flagSaves             = flag.String("saves", "cyoa_saves.json", "The file of the save slots of the terminal mode")
+		flagSecret            = flag.String("secret", os.Getenv("CYOA_SECRET"), "The key signing the state cookies of -http, a random one when empty so restarts reset the readers")
 	)
 	flag.Parse()
 
-	storyFile, err := os.Open(*flagStoryJSONFilename)
+	if *flagHttp {
+		runAsServer(*flagStories, *flagSecret)
+		return
+	}
 
+	story, err := cyoa.LoadStory(*flagStoryJSONFilename)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
-	defer storyFile.Close()
-
-	var story Story
-
-	// deserialize json into struct
-	if err := json.NewDecoder(storyFile).Decode(&story); err != nil {
-		fmt.Println(err)
+	// start with the start arc, run cyoa validate for the other problems
+	if _, ok := story.Arcs[story.FirstArc()]; !ok {
+		fmt.Printf("The story has no %q arc to start with\n", story.FirstArc())
 		return
 	}
 
-	if *flagHttp {
-		http.ListenAndServe(":8080", storyMux(story))
-	} else {
-		runAsCmd(story)
-	}
+	runAsCmd(story, filepath.Clean(*flagStoryJSONFilename), *flagSaves)
 }
 
-func (sh StoryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// get arc from query string
-	arcName := r.URL.Query().Get("arc")
-	if arcName == "" {
-		arcName = "intro"
-	}
-
-	arc, ok := sh.story[arcName]
-	if !ok {
-		http.Error(w, "arc not found", http.StatusNotFound)
-		return
+func runAsServer(dir, secret string) {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			fmt.Printf("Failed to make a secret %v", err)
+			return
+		}
 	}
 
-	// render html
-	templ, err := template.ParseFiles("arc.html")
-
+	lib, err := cyoa.OpenLibrary(dir)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error while rendering HTML: %v", err), http.StatusInternalServerError)
+		fmt.Printf("Failed to load the stories %v\n", err)
 		return
 	}
-
-	// execute template and data binding between struct and template
-	if err := templ.Execute(w, arc); err != nil {
-		http.Error(w, fmt.Sprintf("Error while execute template: %v", err), http.StatusInternalServerError)
+	if err := lib.Watch(); err != nil {
+		fmt.Printf("Failed to watch the stories %v\n", err)
 		return
 	}
-}
+	defer lib.Close()
 
-func storyMux(story Story) http.Handler {
-	return StoryHandler{story}
+	index := template.Must(template.ParseFiles("index.html"))
+	templ := template.Must(template.ParseFiles("arc.html"))
+	http.ListenAndServe(":8080", cyoa.NewHandler(lib, index, templ, key))
 }
 
-func runAsCmd(story Story) {
-	arc := story["intro"]
+func runAsCmd(story cyoa.Story, storyName, savesName string) {
+	st := story.NewState()
 	templ := template.Must(template.ParseFiles("arc.txt"))
+	input := bufio.NewScanner(os.Stdin)
 
 	for {
+		page, err := story.Page(st)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
 		// execute into console window
-		templ.Execute(os.Stdout, arc)
+		templ.Execute(os.Stdout, page)
 
-		if len(arc.Options) == 0 {
-			break
+		next, ok := readChoice(input, story, st, page, storyName, savesName)
+		if !ok {
+			return
 		}
+		st = next // = reassign and don't create new variable
+	}
+}
 
-		fmt.Printf("Choice: ")
-		var choice int
-		for {
-			// scan number
-			if _, err := fmt.Scanf("%d\n", &choice); err != nil {
-				fmt.Println(err)
-				return
-			}
+// readChoice prompts until the reader picks an option, goes back or loads a
+// save, and returns the new state or false when the reader is done
+func readChoice(input *bufio.Scanner, story cyoa.Story, st cyoa.State, page cyoa.Page, storyName, savesName string) (cyoa.State, bool) {
+	for {
+		if len(page.Options) == 0 {
+			fmt.Printf("back, load NAME, saves or enter to quit: ")
+		} else {
+			fmt.Printf("Choice [0-%d], back, save NAME, load NAME or saves: ", len(page.Options)-1)
+		}
+		if !input.Scan() {
+			fmt.Println()
+			return st, false
+		}
+		command, arg, _ := strings.Cut(strings.TrimSpace(input.Text()), " ")
+		arg = strings.TrimSpace(arg)
 
-			if choice < 0 || choice >= len(arc.Options) {
-				fmt.Println("Invalid choice %d, Allowed [0-%d]", choice, len(arc.Options))
+		switch command {
+		case "":
+			if len(page.Options) == 0 {
+				return st, false
+			}
+		case "back":
+			if len(st.History) == 0 {
+				fmt.Println("Nothing to go back to")
+				continue
+			}
+			return st.Back(1), true
+		case "save":
+			if arg == "" {
+				fmt.Println("Missing the name of the save, like save before-the-bridge")
+				continue
+			}
+			slot := cyoa.Slot{Story: storyName, State: st, SavedAt: time.Now()}
+			if err := cyoa.SaveSlot(savesName, arg, slot); err != nil {
+				fmt.Printf("Failed to save %v\n", err)
+				continue
+			}
+			fmt.Printf("Saved as %q in %s\n", arg, savesName)
+		case "load":
+			slots, err := cyoa.LoadSlots(savesName)
+			if err != nil {
+				fmt.Printf("Failed to load the saves %v\n", err)
+				continue
+			}
+			slot, ok := slots[arg]
+			if _, known := story.Arcs[slot.State.Arc]; ok && slot.Story == storyName && known {
+				return story.Resume(slot.State), true
+			}
+			switch {
+			case !ok:
+				fmt.Printf("No save named %q, list them with saves\n", arg)
+			case slot.Story != storyName:
+				fmt.Printf("The save %q is of the story %s\n", arg, slot.Story)
+			default:
+				fmt.Printf("The save %q is on the arc %q the story doesn't have anymore\n", arg, slot.State.Arc)
+			}
+		case "saves":
+			slots, err := cyoa.LoadSlots(savesName)
+			if err != nil {
+				fmt.Printf("Failed to load the saves %v\n", err)
+				continue
+			}
+			if len(slots) == 0 {
+				fmt.Println("No saves yet")
+			}
+			for _, name := range cyoa.SlotNames(slots) {
+				slot := slots[name]
+				fmt.Printf("  %s\t%s, arc %s, saved %s\n", name, slot.Story, slot.State.Arc, slot.SavedAt.Format("2006-01-02 15:04"))
+			}
+		default:
+			choice, err := strconv.Atoi(command)
+			if err != nil || arg != "" || choice < 0 || choice >= len(page.Options) {
+				fmt.Printf("Invalid choice %q\n", input.Text())
 				continue
 			}
-			break
-		}
 
-		arcName := arc.Options[choice].Arc
-		arc = story[arcName] // = reassign and don't create new variable
+			// the options shown are numbered apart from the hidden ones
+			next, err := story.Choose(st, page.Options[choice].Index)
+			if err != nil {
+				fmt.Printf("%v, run cyoa validate to find the broken options\n", err)
+				return st, false
+			}
+			return next, true
+		}
 	}
 }
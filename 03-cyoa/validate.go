@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aboelkassem/gophercises/cyoa/cyoa"
+)
+
+// runValidate checks the given stories, or the ones of the stories
+// directory by default, and exits with status 1 when any of them has a
+// problem
+func runValidate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: cyoa validate [files...]")
+	}
+	fs.Parse(args)
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files, _ = filepath.Glob(filepath.Join("stories", "*.json"))
+	}
+
+	failed := false
+	for _, name := range files {
+		story, err := cyoa.LoadStory(name)
+		if err != nil {
+			fmt.Printf("%s: %v\n", name, err)
+			failed = true
+			continue
+		}
+
+		problems := cyoa.Validate(story)
+		for _, p := range problems {
+			fmt.Printf("%s: %s\n", name, p)
+		}
+		failed = failed || len(problems) > 0
+	}
+
+	if failed {
+		os.Exit(1) // a story has problems
+	}
+}